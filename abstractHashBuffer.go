@@ -1,8 +1,10 @@
 package hashbuffer
 
 import (
+	"context"
+	"fmt"
 	"io"
-	"testing"
+	"log/slog"
 )
 
 // abstractHashBuffer is a base class of HashBuffer.
@@ -18,56 +20,168 @@ type abstractHashBuffer struct {
 	buffer []byte
 	// remains true while stream has data left to read in
 	isOpen bool
+	// set by Close(); the HashBuffer can no longer be used
+	closed bool
 	// current size of the window (may be reduced at the last read)
 	windowSize int
+	// distance between the starts of the windows returned by GetWindow()
+	stride int
+	// reads the stream on a goroutine ahead of the windows; set by WithReadAhead, otherwise nil
+	readAhead *readAheadReader
+	// how room is made in the buffer for more data
+	strategy BufferStrategy
+	// set by WithWindowSizes, with windowSize the largest of them; windows is reused by GetWindows()
+	windowSizes []int
+	windows     [][]byte
+	alignment   Alignment
+	// GetWindows() positions returned where the largest window does not fit: before its first position
+	// (AlignEnd), or after its last one, which starts at edgeBase (AlignStart)
+	edgePositions int
+	edgeBase      int64
+	// set by WithContext; done is nil when the context can never be cancelled
+	ctx  context.Context
+	done <-chan struct{}
+	// the byte just before buffer[0], kept when the buffer is compacted so GetRoll can still report it
+	preceding byte
+	// true once preceding holds a byte from the stream
+	hasPreceding bool
+	// stream offset of buffer[0]
+	bufferOffset int64
+	// total number of bytes read from the stream so far
+	totalRead int64
 
 	reader io.Reader
 	closer io.Closer
+	// set when reader is also an io.Seeker, so Skip can seek rather than read
+	seeker io.Seeker
 
-	// optional testing object to send progress information to
-	t *testing.T // user-supplied; may be left nil
+	// optional logger to send progress information to
+	logger *slog.Logger // user-supplied; may be left nil
 }
 
-// Init initializes an abstractHashBuffer against the specified i/o, with the specified buffer size and window size.
-func (ahb *abstractHashBuffer) init(reader io.Reader, closer io.Closer, bufferSize int, windowSize int) {
+// Init initializes an abstractHashBuffer against the specified i/o, with the buffer size, window size and
+// other settings of the options.
+func (ahb *abstractHashBuffer) init(reader io.Reader, closer io.Closer, o *options) {
 	ahb.reader = reader
 	ahb.closer = closer
-	// The buffer needs to be at least as big as the window size.
-	if bufferSize < windowSize {
-		ahb.bufferSize = windowSize
-	} else {
-		ahb.bufferSize = bufferSize
+	ahb.seeker, _ = reader.(io.Seeker)
+	if o.readAhead {
+		ahb.readAhead = newReadAheadReader(reader, o.bufferSize, o.ctx)
+		ahb.reader = ahb.readAhead
+		// the stream is already past the buffered data, so Skip reads through it
+		ahb.seeker = nil
 	}
+	// The buffer needs to be larger than the window size (newOptions makes sure of it).
+	ahb.bufferSize = max(o.bufferSize, o.windowSize+1)
 	ahb.fillLevel = 0
+	ahb.totalRead = 0
+	ahb.pointer = 0
+	ahb.hasPreceding = false
+	ahb.bufferOffset = 0
+	ahb.windowSize = o.windowSize
+	ahb.apply(o)
+	if ahb.strategy == RingBuffer {
+		ahb.initRing()
+	} else {
+		ahb.buffer = make([]byte, ahb.bufferSize)
+	}
+}
+
+// initWithBuffer initializes an abstractHashBuffer over data that is already entirely in memory.
+// The buffer is used as is, so windows returned are sub-slices of it and nothing is ever read or copied.
+func (ahb *abstractHashBuffer) initWithBuffer(buffer []byte, windowSize int) {
+	ahb.reader = nil
+	ahb.closer = nil
+	ahb.seeker = nil
+	ahb.isOpen = false
+	ahb.buffer = buffer
+	ahb.bufferSize = len(buffer)
+	ahb.fillLevel = len(buffer)
+	ahb.totalRead = int64(len(buffer))
 	ahb.pointer = 0
-	ahb.buffer = make([]byte, bufferSize)
-	ahb.windowSize = windowSize
+	ahb.hasPreceding = false
+	ahb.bufferOffset = 0
+	ahb.stride = 1
+	// the same adjustment fillBuffer makes when the whole stream is shorter than the window
+	if ahb.fillLevel > 0 && ahb.fillLevel < windowSize {
+		ahb.windowSize = ahb.fillLevel
+	} else {
+		ahb.windowSize = windowSize
+	}
 }
 
 // GetWindow returns up to numberOfBytes of data as byte[], along with the number of bytes returned; if no bytes are available, return nil and 0.
+// With a stride (WithStride), the window starts stride bytes after the previous one.
 func (ahb *abstractHashBuffer) GetWindow() (window []byte, err error) {
+	return ahb.GetWindowStride(ahb.stride)
+}
+
+// GetWindowStride returns the window stride bytes after the previous one, whatever the stride set with WithStride;
+// a stride less than 1 is treated as 1.  The first window of the stream is the one at the start.  It returns
+// no window once there is no full window a stride away, except for the one short window of a stream shorter
+// than the window size.
+func (ahb *abstractHashBuffer) GetWindowStride(stride int) (window []byte, err error) {
+	if stride > 1 && ahb.Offset() >= 0 {
+		var ok bool
+		ok, err = ahb.advance(stride - 1)
+		if err != nil || !ok {
+			return
+		}
+	}
+	return ahb.nextWindow()
+}
+
+// advance moves forward count bytes, as Skip does, without the refill logic of Skip when the window
+// count bytes on is already buffered.  ok is false if Skip stopped short, at the last full window.
+func (ahb *abstractHashBuffer) advance(count int) (ok bool, err error) {
+	err = ahb.cancelled()
+	if err != nil {
+		return
+	}
+	if ahb.pointer+count+ahb.windowSize <= ahb.fillLevel {
+		ahb.pointer += count
+		ok = true
+		return
+	}
+	skipped, err := ahb.Skip(count)
+	ok = skipped == count
+	return
+}
+
+// nextWindow returns the window one byte after the previous one, whatever the stride.
+func (ahb *abstractHashBuffer) nextWindow() (window []byte, err error) {
+	if ahb.closed {
+		err = ErrClosed
+		return
+	}
+	err = ahb.cancelled()
+	if err != nil {
+		return
+	}
 	// if ahb.isOpen {
 	// If we need the first read or if the buffer is empty, attempt to read in more data.
-	ahb.logf("GetWindow() starting;  bufferEmpty %v", ahb.bufferEmpty())
 	if ahb.bufferEmpty() {
 		err = ahb.fillBuffer()
 		if err != nil {
-			ahb.logf("GetWindow(): fillBuffer err %v", err)
+			ahb.logf(slog.LevelDebug, "GetWindow(): fillBuffer err %v", err)
 			return
 		}
 		if ahb.bufferEmpty() {
-			ahb.log("GetWindow(): out of data after an attempt to load")
+			ahb.log(slog.LevelDebug, "GetWindow(): out of data after an attempt to load")
 			return
 		}
 	}
-	ahb.logf("available window size: %d", ahb.windowSize)
+	if ahb.strategy == RingBuffer {
+		ahb.wrapRing()
+	}
 	start := ahb.pointer
 	end := ahb.pointer + ahb.windowSize
 	window = ahb.buffer[start:end]
 	// After getting start and end, advance the pointer.
 	ahb.pointer++
-	ahb.logf("start %d  end %d  len %d", start, end, len(ahb.buffer))
-	ahb.logf("val %#x (%s)", window, string(window))
+	if ahb.logEnabled(LevelTrace) {
+		ahb.logf(LevelTrace, "GetWindow(): start %d  end %d  len %d  val %#x (%s)", start, end, len(ahb.buffer), window, string(window))
+	}
 	// }
 	return
 }
@@ -75,152 +189,342 @@ func (ahb *abstractHashBuffer) GetWindow() (window []byte, err error) {
 // GetNext returns the next available byte of data if available and true; if not available return nil and false.
 func (ahb *abstractHashBuffer) GetNext() (nextByte byte, byteAvailable bool, err error) {
 	var window []byte
-	window, err = ahb.GetWindow()
+	window, err = ahb.nextWindow()
 	bytesReceived := len(window)
 	if bytesReceived > 0 {
 		nextByte = window[bytesReceived-1]
 		byteAvailable = true
-		ahb.logf("GetNext returning from %d  len %d", ahb.pointer+ahb.windowSize-1, len(ahb.buffer))
+		if ahb.logEnabled(LevelTrace) {
+			ahb.logf(LevelTrace, "GetNext returning from %d  len %d", ahb.pointer+ahb.windowSize-1, len(ahb.buffer))
+		}
+	}
+	return
+}
+
+// GetRoll returns the byte entering the window (the same byte GetNext returns) and the byte leaving it,
+// which is the first byte of the previous window.  ok is false when no more data is available.
+// At the very start of the stream there is no previous window, so out is 0.
+func (ahb *abstractHashBuffer) GetRoll() (in byte, out byte, ok bool, err error) {
+	in, ok, err = ahb.GetNext()
+	if ok {
+		// the outgoing byte is the one just before the new window, which starts at pointer-1
+		if ahb.pointer > 1 {
+			out = ahb.buffer[ahb.pointer-2]
+		} else if ahb.hasPreceding {
+			out = ahb.preceding
+		}
 	}
 	return
 }
 
 // Skip skips over the next `count` bytes in the input stream.
+// If the stream is an io.Seeker and the skip goes past the buffered data, it seeks rather than reading.
 func (ahb *abstractHashBuffer) Skip(count int) (numberSkipped int, err error) {
-	// if ahb.isOpen {
-	// determine if there is not enough in the buffer currently to skip over
-	if (ahb.pointer + ahb.windowSize + count) > ahb.fillLevel {
-		// attempt to fill buffer
-		err = ahb.fillBuffer()
-		if err != nil {
-			ahb.logf("Skip(): initial fillBuffer err %v", err)
-			return
+	if ahb.closed {
+		err = ErrClosed
+		return
+	}
+	err = ahb.cancelled()
+	if err != nil {
+		return
+	}
+	// set when a fill read nothing, so Skip cannot get any further
+	stalled := false
+	for numberSkipped < count {
+		remaining := count - numberSkipped
+		// determine if there is not enough in the buffer currently to skip over
+		if (ahb.pointer + ahb.windowSize + remaining) > ahb.fillLevel {
+			var sought int
+			sought, err = ahb.seekForward(remaining)
+			if err != nil {
+				ahb.logf(slog.LevelDebug, "Skip(): seek err %v", err)
+				return
+			}
+			if sought > 0 {
+				numberSkipped += sought
+				continue
+			}
+			// attempt to fill buffer
+			read := ahb.totalRead
+			err = ahb.fillBuffer()
+			if err != nil {
+				ahb.logf(slog.LevelDebug, "Skip(): fillBuffer err %v", err)
+				return
+			}
+			if ahb.bufferEmpty() {
+				ahb.log(slog.LevelDebug, "Skip(): out of data after an attempt to load")
+				return
+			}
+			stalled = ahb.totalRead == read
 		}
-		if ahb.bufferEmpty() {
-			ahb.log("Skip(): out of data after an attempt to load")
+		// calculate the amount available to skip
+		amountAvailableToSkip := ahb.fillLevel - (ahb.pointer + ahb.windowSize)
+		if ahb.logEnabled(LevelTrace) {
+			ahb.logf(LevelTrace, "Skip(): amountAvailableToSkip=%d  remaining=%d", amountAvailableToSkip, remaining)
+		}
+		if amountAvailableToSkip <= 0 {
+			// a short read (from a pipe, a socket or the read-ahead goroutine) may only have completed the
+			// next window, so fill again until the stream ends, where the last full window has been reached
+			if ahb.isOpen && !stalled {
+				continue
+			}
 			return
 		}
+		// reduce the amount to skip to the max amount we have available, and advance the pointer by that amount
+		if amountAvailableToSkip > remaining {
+			amountAvailableToSkip = remaining
+		}
+		ahb.pointer += amountAvailableToSkip
+		numberSkipped += amountAvailableToSkip
 	}
-	// calculate the amount available to skip
-	amountAvailableToSkip := ahb.fillLevel - (ahb.pointer + ahb.windowSize)
-	ahb.logf("amountAvailableToSkip=%d", amountAvailableToSkip)
-	// reduce the amount to skip to the max amount we have available
-	if amountAvailableToSkip <= 0 {
-		numberSkipped = 0
+	return
+}
+
+// seekForward skips up to count bytes by seeking the stream, when it is an io.Seeker and the target is
+// beyond the buffered data.  Like Skip, it stops at the start of the last full window.
+// Returns the number skipped; 0 means the caller should read through the data instead.
+func (ahb *abstractHashBuffer) seekForward(count int) (numberSkipped int, err error) {
+	if ahb.seeker == nil || !ahb.isOpen {
 		return
 	}
-	if amountAvailableToSkip < count {
-		numberSkipped = amountAvailableToSkip
-	} else {
-		numberSkipped = count
-	}
-	ahb.logf("numberSkipped=%d", numberSkipped)
-	// advance the pointer by that amount
-	ahb.pointer = ahb.pointer + numberSkipped
-	ahb.logf("recurse=%v", ((!ahb.bufferEmpty()) && (numberSkipped < count)))
-	if (!ahb.bufferEmpty()) && (numberSkipped < count) {
-		// attempt to fill buffer
-		// err = ahb.fillBuffer()
-		// if err != nil {
-		// 	ahb.logf("Skip(): fillBuffer err %v", err)
-		// 	return
-		// }
-		// make the call again until either we reach count,
-		// or we run out of data
-		childCount := count - numberSkipped
-		var childSkipped int
-		ahb.logf("childCount=%d", childCount)
-		childSkipped, err = ahb.Skip(childCount)
-		if err != nil {
-			ahb.logf("Skip(): child Skip() err %v", err)
-		}
-		numberSkipped += childSkipped
+	// stream offsets: the next window start, and the end of the buffered data (the reader's position)
+	start := ahb.bufferOffset + int64(ahb.pointer)
+	buffered := ahb.bufferOffset + int64(ahb.fillLevel)
+	target := start + int64(count)
+	// the byte before the target is read back in for GetRoll, so it must not be buffered already
+	if target-1 < buffered {
+		return
 	}
-	// }
+	current, err := ahb.seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		// not actually seekable (e.g. a pipe); read through the data from now on
+		ahb.logf(slog.LevelDebug, "seekForward(): stream is not seekable: %v", err)
+		ahb.seeker = nil
+		err = nil
+		return
+	}
+	end, err := ahb.seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return
+	}
+	// the last full window starts windowSize bytes before the end of the stream
+	last := buffered + (end - current) - int64(ahb.windowSize)
+	if target > last {
+		target = last
+	}
+	if target <= start || target-1 < buffered {
+		// nothing to gain; put the reader back where it was
+		_, err = ahb.seeker.Seek(current, io.SeekStart)
+		return
+	}
+	_, err = ahb.seeker.Seek(current+(target-1-buffered), io.SeekStart)
+	if err != nil {
+		return
+	}
+	ahb.logf(slog.LevelDebug, "seekForward(): seeking from %d to %d", start, target)
+	// the buffer now holds nothing; the next fill starts with the byte before the target
+	ahb.preceding = 0
+	ahb.hasPreceding = false
+	ahb.bufferOffset = target - 1
+	ahb.fillLevel = 0
+	ahb.pointer = 1
+	numberSkipped = int(target - start)
 	return
 }
 
-// Close the stream if it is not already closed.
+// Offset returns the stream offset of the start of the current window, which is the one most recently
+// returned by GetWindow(), GetNext() or GetRoll(), or stepped over by Skip(); -1 if there is none yet.
+func (ahb *abstractHashBuffer) Offset() int64 {
+	// the pointer is always one past the start of the current window
+	return ahb.bufferOffset + int64(ahb.pointer) - 1
+}
+
+// TotalRead returns the number of bytes read from the stream so far.
+func (ahb *abstractHashBuffer) TotalRead() int64 {
+	return ahb.totalRead
+}
+
+// Close the stream if it is not already closed, and release the buffer.  Only the first call closes the
+// stream and returns its error; afterwards GetWindow(), GetNext(), GetRoll() and Skip() return ErrClosed.
 func (ahb *abstractHashBuffer) Close() (err error) {
+	err = ahb.release()
+	ahb.closed = true
+	ahb.buffer = nil
+	ahb.fillLevel = 0
+	ahb.pointer = 0
+	return
+}
+
+// release closes the stream once it is no longer needed: at the end of the stream, after a read error, or on Close().
+// The buffered data can still be used.  With read-ahead, it waits for the goroutine, which closing the stream
+// interrupts if it is blocked reading a file, pipe or socket.
+func (ahb *abstractHashBuffer) release() (err error) {
 	if ahb.isOpen {
-		err := ahb.closer.Close
-		if err == nil {
-			ahb.isOpen = false
+		// not retried if it fails; the stream is in an unknown state
+		ahb.isOpen = false
+		if ahb.readAhead != nil {
+			ahb.readAhead.cancel()
+		}
+		if ahb.closer != nil {
+			err = ahb.closer.Close()
+		}
+		if ahb.readAhead != nil {
+			ahb.readAhead.wait()
+		}
+		if err != nil {
+			ahb.logf(slog.LevelWarn, "Error %v closing the stream", err)
 		}
 	}
 	return
 }
 
+// SetLogger sets the logger HashBuffer will write information on its progress to; nil disables logging.
+func (ahb *abstractHashBuffer) SetLogger(logger *slog.Logger) {
+	ahb.logger = logger
+}
+
 // SetTesting allows for logging to be sent when testing HashBuffer.
-func (ahb *abstractHashBuffer) SetTesting(t *testing.T) {
-	ahb.t = t
+func (ahb *abstractHashBuffer) SetTesting(t TestingT) {
+	ahb.SetLogger(NewTestingLogger(t))
 }
 
+// maxEmptyReads is the number of reads in a row that may return no data and no error before fillBuffer
+// gives up with io.ErrNoProgress, as bufio does.
+const maxEmptyReads = 100
+
+// fillBuffer makes room in the buffer, then reads until a full window is available after the pointer, the
+// buffer is full, or the stream ends.
 func (ahb *abstractHashBuffer) fillBuffer() (err error) {
-	if ahb.isOpen {
-		// if we reloading the buffer, we need to save the current window and then continue loading
-		if ahb.pointer != 0 {
-			// move the window at the end, to the beginning of the buffer
-			// read in as much as we can after that
-			from := ahb.pointer
-			to := ahb.fillLevel
-			ahb.logf("Preparing buffer to be refilled  from %d (pointer):%d (fillLevel)  to 0  -  new fillLevel %d",
-				from, to, (ahb.fillLevel - ahb.pointer))
-			if to > from {
-				copy(ahb.buffer[0:], ahb.buffer[from:to])
-				ahb.fillLevel = ahb.fillLevel - ahb.pointer
-				ahb.pointer = 0
-				ahb.logf("new fillLevel %d", ahb.fillLevel)
-			}
+	if !ahb.isOpen {
+		ahb.log(slog.LevelDebug, "File is not open.")
+		return
+	}
+	if ahb.strategy == RingBuffer {
+		ahb.wrapRing()
+	} else {
+		ahb.compact()
+	}
+	ahb.log(slog.LevelDebug, "Filling buffer")
+	return ahb.readUntil(ahb.pointer + ahb.windowSize)
+}
+
+// readUntil reads until fillLevel reaches want, there is no more room in the buffer, or the stream ends.
+func (ahb *abstractHashBuffer) readUntil(want int) (err error) {
+	for emptyReads := 0; ; {
+		var space []byte
+		if ahb.strategy == RingBuffer {
+			space = ahb.ringSpace()
+		} else {
+			// beginning just past fillLevel, fill as much of the buffer as we can
+			space = ahb.buffer[ahb.fillLevel:]
 		}
-		ahb.log("Filling buffer")
-		// beginning just past fillLevel, fill as much of the buffer as we can
-		var bytesread int
-		bytesread, err = ahb.reader.Read(ahb.buffer[ahb.fillLevel:])
+		if len(space) == 0 {
+			return
+		}
+		err = ahb.cancelled()
 		if err != nil {
-			if err != io.EOF {
-				ahb.logf("Error %v, closing", err)
-			} else {
-				err = nil
-				ahb.log("End of stream, closing")
+			return
+		}
+		var bytesread int
+		bytesread, err = ahb.reader.Read(space)
+		// a reader may return data along with an error (including io.EOF), so keep what was read either way
+		if bytesread > 0 {
+			if ahb.strategy == RingBuffer {
+				ahb.mirrorRing(space[:bytesread])
 			}
-			ahb.Close()
-		} else {
 			// add the amount read to the fillLevel
 			ahb.fillLevel += bytesread
-			// if the whole stream has already been read and it is less than the window size, adjust the windowsize
-			if ahb.fillLevel < ahb.windowSize {
-				ahb.windowSize = ahb.fillLevel
-			}
+			ahb.totalRead += int64(bytesread)
 			// log amount read and the fillLevel
-			ahb.logf("current fillLevel after read: %d  bytes read: %d\n",
+			ahb.logf(slog.LevelDebug, "current fillLevel after read: %d  bytes read: %d",
 				ahb.fillLevel, bytesread)
 		}
-	} else {
-		ahb.log("File is not open.")
+		if err != nil {
+			if ahb.ctx != nil && err == ahb.ctx.Err() {
+				// cancelled while waiting for the read-ahead goroutine; Close() still closes the stream
+				ahb.logf(slog.LevelDebug, "Cancelled: %v", err)
+				return
+			}
+			if err != io.EOF {
+				ahb.logf(slog.LevelWarn, "Error %v, closing", err)
+				err = &ReadError{Offset: ahb.bufferOffset + int64(ahb.fillLevel), Err: err}
+				ahb.release()
+				return
+			}
+			ahb.log(slog.LevelDebug, "End of stream, closing")
+			err = ahb.release()
+			// if the whole stream has been read and it is less than the window size, adjust the windowsize
+			if ahb.bufferOffset == 0 && ahb.fillLevel > 0 && ahb.fillLevel < ahb.windowSize {
+				ahb.windowSize = ahb.fillLevel
+			}
+			return
+		}
+		// a reader may return less than asked for (a pipe or a socket), so keep reading until there is enough
+		if ahb.fillLevel >= want {
+			return
+		}
+		if bytesread == 0 {
+			emptyReads++
+			if emptyReads == maxEmptyReads {
+				err = &ReadError{Offset: ahb.bufferOffset + int64(ahb.fillLevel), Err: io.ErrNoProgress}
+				return
+			}
+		}
+	}
+}
+
+// compact moves the data from the start of the current window to the beginning of the buffer, to make room after it.
+func (ahb *abstractHashBuffer) compact() {
+	// if we reloading the buffer, we need to save the current window and then continue loading
+	if ahb.pointer != 0 {
+		// move the window at the end, to the beginning of the buffer
+		// read in as much as we can after that
+		from := ahb.pointer
+		to := ahb.fillLevel
+		ahb.logf(slog.LevelDebug, "Preparing buffer to be refilled  from %d (pointer):%d (fillLevel)  to 0  -  new fillLevel %d",
+			from, to, (ahb.fillLevel - ahb.pointer))
+		// (to == from when a one byte window has been used up; there is nothing to move, but still start over)
+		if to >= from {
+			ahb.preceding = ahb.buffer[from-1]
+			ahb.hasPreceding = true
+			copy(ahb.buffer[0:], ahb.buffer[from:to])
+			ahb.bufferOffset += int64(from)
+			ahb.fillLevel = ahb.fillLevel - ahb.pointer
+			ahb.pointer = 0
+			ahb.logf(slog.LevelDebug, "new fillLevel %d", ahb.fillLevel)
+		}
+	}
+}
+
+// cancelled returns the context's error once it has been cancelled (WithContext), otherwise nil.
+func (ahb *abstractHashBuffer) cancelled() error {
+	if ahb.done == nil {
+		return nil
+	}
+	select {
+	case <-ahb.done:
+		return ahb.ctx.Err()
+	default:
+		return nil
 	}
-	return
 }
 
 func (ahb *abstractHashBuffer) bufferEmpty() bool {
-	ahb.logf("Calc bufferEmpty(): fillLevel %d  pointer %d  windowSize %d  LHS %d  RHS %d  bufferEmpty %v",
-		ahb.fillLevel, ahb.pointer, ahb.windowSize,
-		(ahb.pointer + ahb.windowSize),
-		(ahb.fillLevel),
-		(ahb.pointer+ahb.windowSize > ahb.fillLevel))
 	return (ahb.pointer + ahb.windowSize) > ahb.fillLevel
 }
 
-func (ahb *abstractHashBuffer) log(message string) {
-	if ahb.t != nil {
-		ahb.t.Helper()
-		ahb.t.Log(message)
+// logEnabled reports whether a message at level would be logged; check it before building expensive arguments.
+func (ahb *abstractHashBuffer) logEnabled(level slog.Level) bool {
+	return ahb.logger != nil && ahb.logger.Enabled(context.Background(), level)
+}
+
+func (ahb *abstractHashBuffer) log(level slog.Level, message string) {
+	if ahb.logEnabled(level) {
+		ahb.logger.Log(context.Background(), level, message)
 	}
 }
-func (ahb *abstractHashBuffer) logf(format string, args ...interface{}) {
-	if ahb.t != nil {
-		ahb.t.Helper()
-		ahb.t.Logf(format, args...)
-		// fmt.Printf(format+"\n", args...)
+func (ahb *abstractHashBuffer) logf(level slog.Level, format string, args ...interface{}) {
+	if ahb.logEnabled(level) {
+		ahb.logger.Log(context.Background(), level, fmt.Sprintf(format, args...))
 	}
 }
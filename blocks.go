@@ -0,0 +1,60 @@
+package hashbuffer
+
+import (
+	"bytes"
+)
+
+// FirstBlock returns the first window of hb, for reading the stream in consecutive blocks of blockSize
+// bytes; hb must have been created with a window size of blockSize.  The window is a full block, or a
+// shorter one that holds the whole stream, or empty at the end of the stream.  A HashBuffer only
+// shortens its window when the stream is shorter than the window size, so a short window followed by
+// more data means the window size is smaller than blockSize.  ok is false when the window size is not
+// blockSize.  A short block is a copy, since checking that nothing follows it moves on; a full block is
+// only valid until the next call.
+func FirstBlock(hb HashBuffer, blockSize int) (block []byte, ok bool, err error) {
+	block, err = hb.GetWindow()
+	if err != nil || len(block) > blockSize {
+		return
+	}
+	if len(block) == blockSize || len(block) == 0 {
+		ok = true
+		return
+	}
+	block = bytes.Clone(block)
+	next, err := hb.GetWindow()
+	ok = err == nil && len(next) == 0
+	return
+}
+
+// ReadBlocks reads the remaining data of hb in consecutive blocks of blockSize bytes, passing each one to
+// block in turn; hb must have been created with a window size of blockSize.  The last block may be
+// short.  Each block is only valid during the call.  ok is false, and no block is passed, when the window
+// size is not blockSize.
+func ReadBlocks(hb HashBuffer, blockSize int, block func(block []byte)) (ok bool, err error) {
+	window, ok, err := FirstBlock(hb, blockSize)
+	if err != nil || !ok || len(window) == 0 {
+		return
+	}
+	block(window)
+	if len(window) < blockSize {
+		return
+	}
+	// stream offset of the next block
+	next := hb.Offset() + int64(blockSize)
+	for {
+		if _, err = hb.Skip(blockSize - 1); err != nil {
+			return
+		}
+		window, err = hb.GetWindow()
+		if err != nil || len(window) == 0 {
+			return
+		}
+		if start := hb.Offset(); start < next {
+			// Skip() stopped at the last full window; the final short block is at its end
+			block(window[next-start:])
+			return
+		}
+		block(window)
+		next += int64(blockSize)
+	}
+}
@@ -0,0 +1,74 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// Make sure FirstBlock returns a full block, or a short one only when it is the whole stream, and
+// reports a window size that is not the block size.
+func TestFirstBlock(t *testing.T) {
+	for _, test := range []struct {
+		length     int
+		windowSize int
+		blockSize  int
+		want       int
+		ok         bool
+	}{
+		{0, 16, 16, 0, true},
+		{15, 16, 16, 15, true},
+		{16, 16, 16, 16, true},
+		{1000, 16, 16, 16, true},
+		{8, 8, 16, 8, true},
+		{1000, 8, 16, 8, false},
+		{1000, 16, 8, 16, false},
+	} {
+		title := fmt.Sprintf("TestFirstBlock_%d_%d_%d", test.length, test.windowSize, test.blockSize)
+		hb, err := NewReaderHashBuffer(bytes.NewReader(testData[:test.length]), 64, test.windowSize)
+		check(t, err)
+		block, ok, err := FirstBlock(hb, test.blockSize)
+		check(t, err)
+		if ok != test.ok {
+			t.Errorf("Error %s: got ok %t, want %t", title, ok, test.ok)
+		}
+		if ok && !bytes.Equal(block, testData[:test.want]) {
+			t.Errorf("Error %s: got %d bytes %#x, want %d", title, len(block), block, test.want)
+		}
+		closeTestHashBuffer(t, hb)
+	}
+}
+
+// Make sure ReadBlocks passes consecutive blocks covering the whole stream, with only the last one short.
+func TestReadBlocks(t *testing.T) {
+	for _, length := range []int{0, 15, 16, 17, 1000, len(testData)} {
+		for _, bufferSize := range []int{17, 64, 1024} {
+			title := fmt.Sprintf("TestReadBlocks_%d_%d", length, bufferSize)
+			hb, err := NewReaderHashBuffer(bytes.NewReader(testData[:length]), bufferSize, 16)
+			check(t, err)
+			var data []byte
+			blocks := 0
+			ok, err := ReadBlocks(hb, 16, func(block []byte) {
+				if len(block) != 16 && len(data)+len(block) != length {
+					t.Errorf("Error %s: block %d has %d bytes", title, blocks, len(block))
+				}
+				data = append(data, block...)
+				blocks++
+			})
+			check(t, err)
+			if !ok || !bytes.Equal(data, testData[:length]) {
+				t.Errorf("Error %s: got ok %t and %d bytes in %d blocks, want %d bytes", title, ok, len(data), blocks, length)
+			}
+			closeTestHashBuffer(t, hb)
+		}
+	}
+	hb, err := NewReaderHashBuffer(bytes.NewReader(testData), 64, 8)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	ok, err := ReadBlocks(hb, 16, func(block []byte) {
+		t.Errorf("Error TestReadBlocks_mismatched: got a block of %d bytes", len(block))
+	})
+	if err != nil || ok {
+		t.Errorf("Error TestReadBlocks_mismatched: got ok %t, %v, want false", ok, err)
+	}
+}
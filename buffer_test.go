@@ -206,6 +206,29 @@ func TestSkipPastEOF(t *testing.T) {
 	testGetZero(t, hb, title)
 }
 
+// Make sure a window of a single byte reads through the whole file, across buffer refills.
+func TestBufferWindowSizeOne(t *testing.T) {
+	const bufferSize = 1024
+	const windowSize = 1
+	const title = "TestBufferWindowSizeOne"
+
+	t.Logf("start %s", title)
+	hb, err := NewFileHashBuffer("./testdata/data_long", bufferSize, windowSize)
+	check(t, err)
+	defer func() {
+		t.Log("Closing")
+		err := hb.Close()
+		check(t, err)
+	}()
+	for i := 0; i < len(testData); i++ {
+		outByte, _ := testGetNextOne(t, hb, title, testData[i])
+		if outByte != testData[i] {
+			t.Fatalf("Error %s: index %d got %#x, want %#x", title, i, outByte, testData[i])
+		}
+	}
+	testGetNextZero(t, hb, title)
+}
+
 func testBufferFullSizeOfVariousLengthsWithGetNext(t *testing.T, filename string, title string, expectedSize int) {
 	const bufferSize = 1024
 	const windowSize = 16
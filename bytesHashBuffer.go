@@ -0,0 +1,29 @@
+package hashbuffer
+
+// bytesHashBuffer is an in-memory HashBuffer; windows are returned directly from the caller's slice.
+type bytesHashBuffer struct {
+	*abstractHashBuffer
+}
+
+// NewBytesHashBuffer creates a HashBuffer against the specified data, with the specified window size.
+// The data is not copied, so it must not be modified while the HashBuffer is in use.
+func NewBytesHashBuffer(data []byte, windowSize int) (hashBuffer HashBuffer, err error) {
+	return NewBytes(data, WithWindowSize(windowSize))
+}
+
+// NewBytes creates a HashBuffer against the specified data, configured by options; WithWindowSize is
+// required, and WithBufferSize and WithReadAhead do not apply.
+// The data is not copied, so it must not be modified while the HashBuffer is in use.
+func NewBytes(data []byte, options ...Option) (hashBuffer HashBuffer, err error) {
+	o, err := newOptions(options, true)
+	if err != nil {
+		return
+	}
+	bhb := new(bytesHashBuffer)
+	hashBuffer = bhb
+	bhb.abstractHashBuffer = new(abstractHashBuffer)
+
+	bhb.abstractHashBuffer.initWithBuffer(data, o.windowSize)
+	bhb.abstractHashBuffer.apply(o)
+	return
+}
@@ -0,0 +1,110 @@
+package hashbuffer
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Make sure calling GetWindow() and GetNext() on empty data works as expected.
+func TestBytesEmpty(t *testing.T) {
+	const title = "TestBytesEmpty"
+
+	t.Logf("start %s", title)
+	hb := newTestBytesHashBuffer(t, 0)
+	defer closeTestHashBuffer(t, hb)
+	testGetZero(t, hb, fmt.Sprintf("%s first round", title))
+	testGetZero(t, hb, fmt.Sprintf("%s second round", title))
+	testGetNextZero(t, hb, fmt.Sprintf("%s third round", title))
+}
+
+// Make sure calling GetWindow() on 1 byte of data works as expected.
+func TestBytesOneByte(t *testing.T) {
+	const title = "TestBytesOneByte"
+
+	t.Logf("start %s", title)
+	hb := newTestBytesHashBuffer(t, 1)
+	defer closeTestHashBuffer(t, hb)
+	testGet(t, hb, fmt.Sprintf("%s first round", title), testData, 0)
+	testGetZero(t, hb, fmt.Sprintf("%s second round", title))
+	testGetNextZero(t, hb, fmt.Sprintf("%s third round", title))
+}
+
+// Make sure in-memory data of various sizes returns the same windows, and is read the same, as a file.
+func TestBytesCompareSizes(t *testing.T) {
+	testBytesCompareWithFile(t, "./testdata/data_15", "TestBytes_15", 15)
+	testBytesCompareWithFile(t, "./testdata/data_16", "TestBytes_16", 16)
+	testBytesCompareWithFile(t, "./testdata/data_17", "TestBytes_17", 17)
+	testBytesCompareWithFile(t, "./testdata/data_1023", "TestBytes_1023", 1023)
+	testBytesCompareWithFile(t, "./testdata/data_1024", "TestBytes_1024", 1024)
+	testBytesCompareWithFile(t, "./testdata/data_1025", "TestBytes_1025", 1025)
+	testBytesCompareWithFile(t, "./testdata/data_long", "TestBytes_long", 35539)
+}
+
+// Make sure windows are views into the caller's slice rather than copies.
+func TestBytesZeroCopy(t *testing.T) {
+	const windowSize = 16
+	const title = "TestBytesZeroCopy"
+
+	data := testData[:1025]
+	hb, err := NewBytesHashBuffer(data, windowSize)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	for i := 0; i <= len(data)-windowSize; i++ {
+		window, err := hb.GetWindow()
+		check(t, err)
+		if len(window) != windowSize || &window[0] != &data[i] {
+			t.Fatalf("Error %s: window %d is not a view into the data", title, i)
+		}
+	}
+	testGetZero(t, hb, title)
+}
+
+// Test that Skip() works the same way against in-memory data as it does against a file.
+func TestBytesSkip(t *testing.T) {
+	const title = "TestBytesSkip"
+
+	t.Logf("start %s", title)
+	hb := newTestBytesHashBuffer(t, 1025)
+	defer closeTestHashBuffer(t, hb)
+	skipped, err := hb.Skip(1010)
+	check(t, err)
+	if skipped != 1009 {
+		t.Errorf("Error %s: skipped %d, should have skipped 1009", title, skipped)
+	}
+	testGet(t, hb, title, testData, 1009)
+	testGetZero(t, hb, title)
+}
+
+func testBytesCompareWithFile(t *testing.T, filename string, title string, expectedSize int) {
+	const bufferSize = 1024
+	const windowSize = 16
+
+	t.Logf("start %s", title)
+	hb := newTestBytesHashBuffer(t, expectedSize)
+	defer closeTestHashBuffer(t, hb)
+	fhb, err := NewFileHashBuffer(filename, bufferSize, windowSize)
+	check(t, err)
+	defer closeTestHashBuffer(t, fhb)
+	for i := 0; ; i++ {
+		window, err := hb.GetWindow()
+		check(t, err)
+		want, err := fhb.GetWindow()
+		check(t, err)
+		if !testEq(window, want) {
+			t.Fatalf("Error %s: window %d is %#x, want %#x", title, i, window, want)
+		}
+		if len(want) == 0 {
+			break
+		}
+	}
+	testGetNextZero(t, hb, fmt.Sprintf("%s final round", title))
+}
+
+// newTestBytesHashBuffer returns a HashBuffer over the first size bytes of testData.
+func newTestBytesHashBuffer(t *testing.T, size int) HashBuffer {
+	const windowSize = 16
+
+	hb, err := NewBytesHashBuffer(testData[:size], windowSize)
+	check(t, err)
+	return hb
+}
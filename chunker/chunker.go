@@ -0,0 +1,133 @@
+// Package chunker splits a stream, read through a hashbuffer.HashBuffer, into content-defined chunks.
+//
+// A chunk boundary is placed wherever the rolling hash of the window of the HashBuffer ending at a byte
+// matches a pattern, so boundaries depend only on nearby content: inserting or removing data only
+// changes the chunks around the edit, which is what makes the chunks useful for deduplication.
+package chunker
+
+import (
+	"crypto/sha256"
+	"errors"
+	"iter"
+	"math/bits"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+	"github.com/kagalle/go-hashbuffer/rolling"
+)
+
+// Chunk describes one chunk of the stream.
+type Chunk struct {
+	// stream offset of the first byte of the chunk
+	Offset int64
+	// number of bytes in the chunk
+	Length int
+	// SHA-256 of the chunk's data
+	Hash [sha256.Size]byte
+}
+
+// Config sets the size limits of chunks.
+type Config struct {
+	// no boundary is placed before a chunk is this long (except at the end of the stream)
+	MinSize int
+	// the expected chunk size; it is rounded down to a power of two
+	AvgSize int
+	// a boundary is always placed when a chunk reaches this length
+	MaxSize int
+}
+
+// DefaultConfig gives chunks of 2 KiB to 64 KiB, 8 KiB on average.
+var DefaultConfig = Config{MinSize: 2 << 10, AvgSize: 8 << 10, MaxSize: 64 << 10}
+
+// ErrInvalidConfig is returned when the sizes of a Config are not 0 < MinSize <= AvgSize <= MaxSize.
+var ErrInvalidConfig = errors.New("chunker: sizes must satisfy 0 < MinSize <= AvgSize <= MaxSize")
+
+func (config Config) validate() error {
+	if config.MinSize <= 0 || config.MinSize > config.AvgSize || config.AvgSize > config.MaxSize {
+		return ErrInvalidConfig
+	}
+	return nil
+}
+
+// avgBits returns log2 of AvgSize, rounded down.
+func (config Config) avgBits() int {
+	return bits.Len(uint(config.AvgSize)) - 1
+}
+
+// chunks returns an iterator over the chunks of hb, with a boundary after any byte where isBoundary
+// reports true for the hash of the window ending at that byte and the length the chunk would have.
+func chunks(hb hashbuffer.HashBuffer, config Config, hasher rolling.RollingHasher,
+	isBoundary func(hash uint64, length int) bool) iter.Seq2[Chunk, error] {
+	return func(yield func(Chunk, error) bool) {
+		if err := config.validate(); err != nil {
+			yield(Chunk{}, err)
+			return
+		}
+		var offset int64
+		data := make([]byte, 0, config.MaxSize)
+		emit := func() bool {
+			chunk := Chunk{Offset: offset, Length: len(data), Hash: sha256.Sum256(data)}
+			offset += int64(len(data))
+			data = data[:0]
+			return yield(chunk, nil)
+		}
+		// add appends one byte to the current chunk, emitting the chunk if this is a boundary;
+		// returns false when the consumer has stopped iterating
+		add := func(b byte, hashed bool, hash uint64) bool {
+			data = append(data, b)
+			if len(data) >= config.MaxSize ||
+				(hashed && len(data) >= config.MinSize && isBoundary(hash, len(data))) {
+				return emit()
+			}
+			return true
+		}
+
+		window, err := hb.GetWindow()
+		if err != nil {
+			yield(Chunk{}, err)
+			return
+		}
+		if len(window) == 0 {
+			return
+		}
+		offset = hb.Offset()
+		hasher.Reset(window)
+		// there is no full window, and so no hash, until the last byte of the first window
+		for i, b := range window {
+			if !add(b, i == len(window)-1, hasher.Sum64()) {
+				return
+			}
+		}
+		for {
+			in, out, ok, err := hb.GetRoll()
+			if err != nil {
+				yield(Chunk{}, err)
+				return
+			}
+			if !ok {
+				break
+			}
+			hasher.Roll(out, in)
+			if !add(in, true, hasher.Sum64()) {
+				return
+			}
+		}
+		if len(data) > 0 {
+			emit()
+		}
+	}
+}
+
+// spreadMask returns a mask of n bits spread evenly over the top 48 bits of a uint64.
+// The low bits of hashes that shift left (such as Gear) only depend on the last few bytes.
+func spreadMask(n int) (mask uint64) {
+	if n <= 0 {
+		return
+	}
+	if n > 48 {
+		n = 48
+	}
+	for i := 0; i < n; i++ {
+		mask |= 1 << (63 - i*48/n)
+	}
+	return
+}
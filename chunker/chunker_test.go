@@ -0,0 +1,177 @@
+package chunker
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"testing"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+)
+
+var testConfig = Config{MinSize: 256, AvgSize: 1024, MaxSize: 4096}
+
+type chunkFunc func(hb hashbuffer.HashBuffer, config Config) iter.Seq2[Chunk, error]
+
+var testChunkers = []struct {
+	name       string
+	chunk      chunkFunc
+	windowSize int
+}{
+	{"FastCDC", FastCDC, FastCDCWindowSize},
+	{"RabinCDC", RabinCDC, RabinWindowSize},
+}
+
+// Make sure chunks cover the whole file, respect the size limits, and carry the hash of their data.
+func TestChunks(t *testing.T) {
+	data := readTestData(t, "../testdata/data_long")
+	for _, tc := range testChunkers {
+		title := fmt.Sprintf("TestChunks_%s", tc.name)
+		chunks := collectChunks(t, tc.chunk, newFileHashBuffer(t, "../testdata/data_long", 1024, tc.windowSize), testConfig)
+		var offset int64
+		for i, chunk := range chunks {
+			if chunk.Offset != offset {
+				t.Fatalf("Error %s: chunk %d at %d, want %d", title, i, chunk.Offset, offset)
+			}
+			if chunk.Length > testConfig.MaxSize || (chunk.Length < testConfig.MinSize && i != len(chunks)-1) {
+				t.Errorf("Error %s: chunk %d has length %d", title, i, chunk.Length)
+			}
+			if chunk.Hash != sha256.Sum256(data[offset:offset+int64(chunk.Length)]) {
+				t.Errorf("Error %s: chunk %d has the wrong hash", title, i)
+			}
+			offset += int64(chunk.Length)
+		}
+		if offset != int64(len(data)) {
+			t.Errorf("Error %s: chunks cover %d bytes, want %d", title, offset, len(data))
+		}
+		// content-defined boundaries should make some chunks end before MaxSize
+		if len(chunks) <= len(data)/testConfig.MaxSize+1 {
+			t.Errorf("Error %s: only %d chunks", title, len(chunks))
+		}
+		t.Logf("%s: %d chunks", title, len(chunks))
+	}
+}
+
+// Make sure chunking is deterministic: the same for any buffer size or HashBuffer implementation,
+// and the same as the boundaries recorded when the algorithms were written.
+func TestChunksDeterministic(t *testing.T) {
+	golden := map[string][]int64{
+		"FastCDC":  goldenFastCDC,
+		"RabinCDC": goldenRabinCDC,
+	}
+	data := readTestData(t, "../testdata/data_long")
+	for _, tc := range testChunkers {
+		title := fmt.Sprintf("TestChunksDeterministic_%s", tc.name)
+		want := collectChunks(t, tc.chunk, newFileHashBuffer(t, "../testdata/data_long", 1024, tc.windowSize), testConfig)
+		for _, bufferSize := range []int{tc.windowSize, 100, 4096, 65536} {
+			got := collectChunks(t, tc.chunk, newFileHashBuffer(t, "../testdata/data_long", bufferSize, tc.windowSize), testConfig)
+			compareChunks(t, fmt.Sprintf("%s_%d", title, bufferSize), got, want)
+		}
+		hb, err := hashbuffer.NewBytesHashBuffer(data, tc.windowSize)
+		check(t, err)
+		compareChunks(t, title+"_bytes", collectChunks(t, tc.chunk, hb, testConfig), want)
+
+		if len(want) != len(golden[tc.name]) {
+			t.Fatalf("Error %s: got %d chunks, want %d", title, len(want), len(golden[tc.name]))
+		}
+		for i, chunk := range want {
+			if chunk.Offset != golden[tc.name][i] {
+				t.Errorf("Error %s: chunk %d at %d, want %d", title, i, chunk.Offset, golden[tc.name][i])
+			}
+		}
+	}
+}
+
+// Make sure an insertion near the start of the data only changes the chunks around it.
+func TestChunksShift(t *testing.T) {
+	data := readTestData(t, "../testdata/data_long")
+	edited := append(append(append([]byte{}, data[:100]...), "inserted text"...), data[100:]...)
+	for _, tc := range testChunkers {
+		title := fmt.Sprintf("TestChunksShift_%s", tc.name)
+		hb, err := hashbuffer.NewBytesHashBuffer(data, tc.windowSize)
+		check(t, err)
+		original := collectChunks(t, tc.chunk, hb, testConfig)
+		hb, err = hashbuffer.NewBytesHashBuffer(edited, tc.windowSize)
+		check(t, err)
+		shifted := collectChunks(t, tc.chunk, hb, testConfig)
+		seen := make(map[[sha256.Size]byte]bool)
+		for _, chunk := range original {
+			seen[chunk.Hash] = true
+		}
+		shared := 0
+		for _, chunk := range shifted {
+			if seen[chunk.Hash] {
+				shared++
+			}
+		}
+		if shared < len(original)-2 {
+			t.Errorf("Error %s: only %d of %d chunks unchanged", title, shared, len(original))
+		}
+	}
+}
+
+// Make sure the end cases work: empty data, data shorter than the window, and invalid sizes.
+func TestChunksEdgeCases(t *testing.T) {
+	for _, tc := range testChunkers {
+		title := fmt.Sprintf("TestChunksEdgeCases_%s", tc.name)
+		if chunks := collectChunks(t, tc.chunk, newFileHashBuffer(t, "../testdata/data_0", 1024, tc.windowSize), testConfig); len(chunks) != 0 {
+			t.Errorf("Error %s: got %d chunks for empty data", title, len(chunks))
+		}
+		chunks := collectChunks(t, tc.chunk, newFileHashBuffer(t, "../testdata/data_17", 1024, tc.windowSize), testConfig)
+		if len(chunks) != 1 || chunks[0].Offset != 0 || chunks[0].Length != 17 {
+			t.Errorf("Error %s: got %v for 17 bytes of data", title, chunks)
+		}
+		for _, config := range []Config{{}, {MinSize: 2, AvgSize: 1, MaxSize: 4}, {MinSize: 1, AvgSize: 4, MaxSize: 2}} {
+			for _, err := range tc.chunk(newFileHashBuffer(t, "../testdata/data_17", 1024, tc.windowSize), config) {
+				if !errors.Is(err, ErrInvalidConfig) {
+					t.Errorf("Error %s: got %v for %+v", title, err, config)
+				}
+			}
+		}
+	}
+}
+
+func compareChunks(t *testing.T, title string, got []Chunk, want []Chunk) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("Error %s: got %d chunks, want %d", title, len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("Error %s: chunk %d is %+v, want %+v", title, i, got[i], want[i])
+		}
+	}
+}
+
+func collectChunks(t *testing.T, chunk chunkFunc, hb hashbuffer.HashBuffer, config Config) (chunks []Chunk) {
+	t.Helper()
+	defer hb.Close()
+	for c, err := range chunk(hb, config) {
+		check(t, err)
+		chunks = append(chunks, c)
+	}
+	return
+}
+
+func newFileHashBuffer(t *testing.T, filename string, bufferSize int, windowSize int) hashbuffer.HashBuffer {
+	t.Helper()
+	hb, err := hashbuffer.NewFileHashBuffer(filename, bufferSize, windowSize)
+	check(t, err)
+	return hb
+}
+
+func readTestData(t *testing.T, filename string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	check(t, err)
+	return data
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Error %v", err)
+	}
+}
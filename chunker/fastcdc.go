@@ -0,0 +1,28 @@
+package chunker
+
+import (
+	"iter"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+	"github.com/kagalle/go-hashbuffer/rolling"
+)
+
+// FastCDCWindowSize is the window size to create the HashBuffer with for FastCDC; the Gear hash
+// it uses only depends on the last 64 bytes.
+const FastCDCWindowSize = 64
+
+// FastCDC returns an iterator over the chunks of hb using the FastCDC algorithm: a Gear rolling hash,
+// with normalized chunking so that chunk sizes cluster around AvgSize.  Before a chunk reaches AvgSize,
+// a boundary needs two more hash bits to match than the average would suggest, and after it, two fewer.
+// Iteration ends at the end of the stream, or after yielding an error.
+func FastCDC(hb hashbuffer.HashBuffer, config Config) iter.Seq2[Chunk, error] {
+	avgBits := config.avgBits()
+	maskSmall := spreadMask(avgBits + 2)
+	maskLarge := spreadMask(avgBits - 2)
+	return chunks(hb, config, rolling.NewGear(), func(hash uint64, length int) bool {
+		if length < config.AvgSize {
+			return hash&maskSmall == 0
+		}
+		return hash&maskLarge == 0
+	})
+}
@@ -0,0 +1,11 @@
+package chunker
+
+// Chunk offsets of testdata/data_long with testConfig, recorded when the algorithms were written.
+// A change here means previously stored chunks would no longer be found.
+var goldenFastCDC = []int64{0, 1083, 2134, 3212, 3956, 5159, 5583, 7154, 8191, 9242, 10320, 11064, 12267,
+	12691, 14262, 15299, 16350, 17428, 18172, 19375, 19799, 21370, 22407, 23458, 24536, 25280, 26483, 26907,
+	28478, 29515, 30566, 31644, 32388, 33591, 34015}
+
+var goldenRabinCDC = []int64{0, 463, 2290, 2657, 4229, 4709, 5509, 5970, 7159, 7571, 9398, 9765, 11337, 11817,
+	12617, 13078, 14267, 14679, 16506, 16873, 18445, 18925, 19725, 20186, 21375, 21787, 23614, 23981, 25553,
+	26033, 26833, 27294, 28483, 28895, 30722, 31089, 32661, 33141, 33941, 34402}
@@ -0,0 +1,23 @@
+package chunker
+
+import (
+	"iter"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+	"github.com/kagalle/go-hashbuffer/rolling"
+)
+
+// RabinWindowSize is the window size to create the HashBuffer with for RabinCDC, as used by LBFS.
+const RabinWindowSize = 48
+
+// RabinCDC returns an iterator over the chunks of hb using classic Rabin-based content-defined chunking:
+// a boundary is placed where the low log2(AvgSize) bits of the Rabin fingerprint of the window, modulo
+// rolling.DefaultRabinPolynomial, are all zero.  Iteration ends at the end of the stream, or after yielding
+// an error.
+func RabinCDC(hb hashbuffer.HashBuffer, config Config) iter.Seq2[Chunk, error] {
+	// an invalid config, whose avgBits() may be negative, is rejected by chunks() before the mask is used
+	mask := uint64(1)<<uint(config.avgBits()) - 1
+	return chunks(hb, config, rolling.NewRabin(), func(hash uint64, length int) bool {
+		return hash&mask == 0
+	})
+}
@@ -0,0 +1,124 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+var errTestClose = errors.New("test close error")
+
+// testCloser is a reader that counts how many times it is closed.
+type testCloser struct {
+	*bytes.Reader
+	closed int
+	err    error
+}
+
+func (c *testCloser) Close() error {
+	c.closed++
+	return c.err
+}
+
+// Make sure a reader that is an io.Closer is closed exactly once by Close(), which returns its error.
+func TestReaderClose(t *testing.T) {
+	const title = "TestReaderClose"
+
+	closer := &testCloser{Reader: bytes.NewReader(testData), err: errTestClose}
+	hb, err := NewReaderHashBuffer(closer, 1024, 16)
+	check(t, err)
+	testGet(t, hb, title, testData, 0)
+	if err = hb.Close(); !errors.Is(err, errTestClose) {
+		t.Errorf("Error %s: first Close() returned %v, want the reader's error", title, err)
+	}
+	if err = hb.Close(); err != nil {
+		t.Errorf("Error %s: second Close() returned %v", title, err)
+	}
+	if closer.closed != 1 {
+		t.Errorf("Error %s: reader closed %d times", title, closer.closed)
+	}
+	testClosed(t, hb, title)
+}
+
+// Make sure the reader is closed once the end of the stream is reached, and not again by Close(),
+// and that the data buffered is still returned before then.
+func TestReaderCloseAtEnd(t *testing.T) {
+	const title = "TestReaderCloseAtEnd"
+
+	closer := &testCloser{Reader: bytes.NewReader(testData[:100])}
+	hb, err := NewReaderHashBuffer(closer, 1024, 16)
+	check(t, err)
+	testGet(t, hb, title, testData, 0)
+	skipped, err := hb.Skip(1000)
+	check(t, err)
+	if skipped != 83 || closer.closed != 1 {
+		t.Errorf("Error %s: skipped %d and closed %d times at the end of the stream", title, skipped, closer.closed)
+	}
+	testGet(t, hb, title, testData, 84)
+	testGetZero(t, hb, title)
+	closeTestHashBuffer(t, hb)
+	if closer.closed != 1 {
+		t.Errorf("Error %s: reader closed %d times", title, closer.closed)
+	}
+	testClosed(t, hb, title)
+}
+
+// Make sure Close() releases the file, whether or not it was read to the end.
+func TestFileClose(t *testing.T) {
+	const title = "TestFileClose"
+
+	for _, read := range []bool{false, true} {
+		hb, err := NewFileHashBuffer("./testdata/data_long", 1024, 16)
+		check(t, err)
+		if read {
+			_, err = hb.Skip(len(testData))
+			check(t, err)
+		}
+		closeTestHashBuffer(t, hb)
+		// closing the file again fails only if Close() really closed it
+		if err = hb.(*fileHashBuffer).file.Close(); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("Error %s: file still open after Close(): %v", title, err)
+		}
+		check(t, hb.Close())
+		testClosed(t, hb, title)
+	}
+}
+
+// Make sure no file descriptors are left open by many HashBuffers, as in a long-running process.
+func TestFileCloseDescriptors(t *testing.T) {
+	const title = "TestFileCloseDescriptors"
+
+	before, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skipf("%s: open file descriptors cannot be listed: %v", title, err)
+	}
+	for i := 0; i < 200; i++ {
+		hb, err := NewFileHashBuffer("./testdata/data_long", 1024, 16)
+		check(t, err)
+		testGet(t, hb, title, testData, 0)
+		closeTestHashBuffer(t, hb)
+	}
+	after, err := os.ReadDir("/proc/self/fd")
+	check(t, err)
+	if len(after) > len(before) {
+		t.Errorf("Error %s: %d file descriptors open before, %d after", title, len(before), len(after))
+	}
+}
+
+// testClosed checks that a HashBuffer that has been closed returns ErrClosed.
+func testClosed(t *testing.T, hb HashBuffer, title string) {
+	t.Helper()
+	if window, err := hb.GetWindow(); !errors.Is(err, ErrClosed) || len(window) != 0 {
+		t.Errorf("Error %s: GetWindow() after Close() returned %d bytes and %v", title, len(window), err)
+	}
+	if _, ok, err := hb.GetNext(); !errors.Is(err, ErrClosed) || ok {
+		t.Errorf("Error %s: GetNext() after Close() returned %v and %v", title, ok, err)
+	}
+	if _, _, ok, err := hb.GetRoll(); !errors.Is(err, ErrClosed) || ok {
+		t.Errorf("Error %s: GetRoll() after Close() returned %v and %v", title, ok, err)
+	}
+	if skipped, err := hb.Skip(10); !errors.Is(err, ErrClosed) || skipped != 0 {
+		t.Errorf("Error %s: Skip() after Close() returned %d and %v", title, skipped, err)
+	}
+}
@@ -0,0 +1,134 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowReader returns one small read at a time after a delay, and blocks once its data is used up until it is closed.
+type slowReader struct {
+	data      []byte
+	delay     time.Duration
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newSlowReader(data []byte, delay time.Duration) *slowReader {
+	return &slowReader{data: data, delay: delay, closed: make(chan struct{})}
+}
+
+func (r *slowReader) Read(p []byte) (n int, err error) {
+	if len(r.data) == 0 {
+		<-r.closed
+		return 0, io.ErrClosedPipe
+	}
+	select {
+	case <-time.After(r.delay):
+	case <-r.closed:
+		return 0, io.ErrClosedPipe
+	}
+	n = copy(p, r.data[:min(len(r.data), 8)])
+	r.data = r.data[n:]
+	return
+}
+
+func (r *slowReader) Close() error {
+	r.closeOnce.Do(func() { close(r.closed) })
+	return nil
+}
+
+// Make sure each call returns the context's error once it is cancelled, even with data still buffered.
+func TestContextCancel(t *testing.T) {
+	const title = "TestContextCancel"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hb, err := NewReader(bytes.NewReader(testData), WithWindowSize(16), WithContext(ctx))
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	testGet(t, hb, title, testData, 0)
+	cancel()
+	if _, err = hb.GetWindow(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Error %s: GetWindow() got %v, want context.Canceled", title, err)
+	}
+	if _, _, err = hb.GetNext(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Error %s: GetNext() got %v, want context.Canceled", title, err)
+	}
+	if _, _, _, err = hb.GetRoll(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Error %s: GetRoll() got %v, want context.Canceled", title, err)
+	}
+	if _, err = hb.Skip(100); !errors.Is(err, context.Canceled) {
+		t.Errorf("Error %s: Skip() got %v, want context.Canceled", title, err)
+	}
+	testOffset(t, hb, title, 0)
+}
+
+// Make sure a scan of a slow reader stops at the next read once the deadline passes.
+func TestContextDeadline(t *testing.T) {
+	const title = "TestContextDeadline"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	reader := newSlowReader(testData, 5*time.Millisecond)
+	hb, err := NewReader(reader, WithBufferSize(64), WithWindowSize(16), WithContext(ctx))
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	start := time.Now()
+	windows := 0
+	for _, err = range hb.Windows() {
+		if err != nil {
+			break
+		}
+		windows++
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Error %s: got %v after %d windows, want context.DeadlineExceeded", title, err, windows)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Error %s: took %v to stop", title, elapsed)
+	}
+}
+
+// Make sure a call waiting for the read-ahead goroutine returns as soon as the context is cancelled,
+// and Close() still stops the goroutine afterwards.
+func TestContextReadAhead(t *testing.T) {
+	const title = "TestContextReadAhead"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// the reader blocks after the first 200 bytes, so the second Skip() waits on the goroutine
+	reader := newSlowReader(testData[:200], time.Millisecond)
+	hb, err := NewReader(reader, WithBufferSize(64), WithWindowSize(16), WithReadAhead(true), WithContext(ctx))
+	check(t, err)
+	_, err = hb.Skip(100)
+	check(t, err)
+	errs := make(chan error)
+	go func() {
+		_, err := hb.Skip(100)
+		errs <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case err = <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Error %s: Skip() got %v, want context.Canceled", title, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Error %s: Skip() did not return after cancellation", title)
+	}
+	check(t, hb.Close())
+	testClosed(t, hb, title)
+}
+
+// Make sure WithContext rejects a nil context.
+func TestContextNil(t *testing.T) {
+	//lint:ignore SA1012 testing that nil is rejected
+	hb, err := NewReader(bytes.NewReader(testData), WithWindowSize(16), WithContext(nil))
+	if !errors.Is(err, ErrInvalidOption) || hb != nil {
+		t.Errorf("Error TestContextNil: got %v, want ErrInvalidOption", err)
+	}
+}
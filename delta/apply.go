@@ -0,0 +1,37 @@
+package delta
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrInvalidDelta is returned when a delta is malformed or refers to data outside the old stream.
+var ErrInvalidDelta = errors.New("delta: invalid delta")
+
+// Apply rebuilds the new stream from the old stream and the delta, writing it to out.
+func Apply(old io.ReaderAt, delta Delta, out io.Writer) (err error) {
+	for _, op := range delta {
+		switch op.Type {
+		case OpCopy:
+			if op.Offset < 0 || op.Length < 0 {
+				return ErrInvalidDelta
+			}
+			var copied int64
+			copied, err = io.Copy(out, io.NewSectionReader(old, op.Offset, op.Length))
+			if err != nil {
+				return
+			}
+			if copied != op.Length {
+				return ErrInvalidDelta
+			}
+		case OpLiteral:
+			_, err = out.Write(op.Data)
+			if err != nil {
+				return
+			}
+		default:
+			return ErrInvalidDelta
+		}
+	}
+	return
+}
@@ -0,0 +1,186 @@
+// Package delta computes rsync-style deltas: given the signature of an old stream, a new stream read
+// through a hashbuffer.HashBuffer is scanned for blocks of the old stream at any offset, and described as
+// a list of operations that copy ranges of the old stream or insert literal data.
+package delta
+
+import (
+	"bytes"
+	"errors"
+	"hash"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+	"github.com/kagalle/go-hashbuffer/rolling"
+	"github.com/kagalle/go-hashbuffer/signature"
+)
+
+// OpType identifies the kind of an Op.
+type OpType byte
+
+const (
+	// OpCopy copies Length bytes starting at Offset in the old stream.
+	OpCopy OpType = iota + 1
+	// OpLiteral inserts Data.
+	OpLiteral
+)
+
+// Op is one operation of a Delta.
+type Op struct {
+	Type OpType
+	// OpCopy: offset in the old stream
+	Offset int64
+	// OpCopy: number of bytes to copy
+	Length int64
+	// OpLiteral: data to insert
+	Data []byte
+}
+
+// Delta is the list of operations that rebuild the new stream from the old one.
+// Adjacent copies of contiguous ranges, and adjacent literals, are always merged.
+type Delta []Op
+
+// ErrMismatchedSignature is returned when the HashBuffer's window size or the strong hash do not match the signature.
+var ErrMismatchedSignature = errors.New("delta: window size and strong hash must match the signature")
+
+// Compute scans the remaining data in hb, which must have been created with a window size of the
+// signature's block size, for blocks of the old stream described by sig.  strongHash must be the hash
+// the signature was generated with.  Full blocks are found at any offset; a short last block is only
+// found at the end of the new stream.
+func Compute(hb hashbuffer.HashBuffer, sig *signature.Signature, strongHash func() hash.Hash) (delta Delta, err error) {
+	strong := strongHash()
+	if sig.BlockSize <= 0 || strong.Size() != sig.StrongSize {
+		err = ErrMismatchedSignature
+		return
+	}
+	index := NewIndex(sig.Blocks, strong, sig.StrongSize)
+
+	window, ok, err := hashbuffer.FirstBlock(hb, sig.BlockSize)
+	if err != nil {
+		return
+	}
+	if !ok {
+		err = ErrMismatchedSignature
+		return
+	}
+	if len(window) == 0 {
+		return
+	}
+	if len(window) < sig.BlockSize {
+		// the whole stream is shorter than a block
+		delta.addRest(sig, index, window)
+		return
+	}
+	rest, err := Scan(hb, window, rolling.NewAdler32(),
+		func(window []byte, sum uint64) (next int64, ok bool, err error) {
+			// a short last block is only looked for at the end, by addRest
+			if block, found := index.Match(window, uint32(sum)); found && sig.BlockLength(block) == sig.BlockSize {
+				delta.AddCopy(int64(block)*int64(sig.BlockSize), int64(sig.BlockSize))
+				next, ok = hb.Offset()+int64(sig.BlockSize), true
+			}
+			return
+		}, func(b byte) { delta.AddLiteral(b) })
+	if err != nil {
+		return
+	}
+	delta.addRest(sig, index, rest)
+	return
+}
+
+// Scan rolls hasher over the remaining data in hb, starting with window, a full window just returned by
+// hb.GetWindow().  match is called with each window and its hash.  When it finds a match, it returns the
+// stream offset where the scan goes on, which must be after the start of the current window (see
+// Offset()) and no later than its end; match may move hb forward with GetNext() first.  Otherwise the
+// first byte of the window is passed to literal, and the window slides on by one byte.  rest is the data
+// at the end of the stream, shorter than a window, that was neither matched nor passed to literal; it is
+// only valid until the next call to hb.
+func Scan(hb hashbuffer.HashBuffer, window []byte, hasher rolling.RollingHasher,
+	match func(window []byte, sum uint64) (next int64, ok bool, err error), literal func(b byte)) (rest []byte, err error) {
+	size := len(window)
+	hasher.Reset(window)
+	// a copy of the current window, kept when the next GetWindow() has to refill the buffer (which
+	// overwrites it) in case the stream ends there
+	var saved []byte
+	for {
+		var next int64
+		var ok bool
+		if next, ok, err = match(window, hasher.Sum64()); err != nil {
+			return
+		}
+		if ok {
+			// the end of the current window after next, which is the rest if the stream ends with it
+			var tail []byte
+			if ahead := next - hb.Offset(); ahead < int64(size) {
+				if tail, err = hb.Peek(size); err != nil {
+					return
+				}
+				tail = bytes.Clone(tail[ahead:])
+			}
+			if _, err = hb.Skip(int(next - hb.Offset() - 1)); err != nil {
+				return
+			}
+			if window, err = hb.GetWindow(); err != nil {
+				return
+			}
+			if len(window) == 0 {
+				rest = tail
+				return
+			}
+			if hb.Offset() < next {
+				// Skip() stopped at the last full window; only its end follows the match
+				rest = window[next-hb.Offset():]
+				return
+			}
+			hasher.Reset(window)
+			continue
+		}
+		out := window[0]
+		if hb.Offset()+int64(size) == hb.TotalRead() {
+			saved = append(saved[:0], window...)
+		}
+		if window, err = hb.GetWindow(); err != nil {
+			return
+		}
+		if len(window) == 0 {
+			rest = saved
+			return
+		}
+		literal(out)
+		hasher.Roll(out, window[size-1])
+	}
+}
+
+// addRest adds the data at the end of the new stream, which may end with the old stream's short last block.
+func (delta *Delta) addRest(sig *signature.Signature, index *Index, rest []byte) {
+	last := len(sig.Blocks) - 1
+	if last >= 0 {
+		length := sig.BlockLength(last)
+		if length < sig.BlockSize && length <= len(rest) && index.Equal(rest[len(rest)-length:], last) {
+			delta.AddLiteral(rest[:len(rest)-length]...)
+			delta.AddCopy(int64(last)*int64(sig.BlockSize), int64(length))
+			return
+		}
+	}
+	delta.AddLiteral(rest...)
+}
+
+// AddCopy appends an operation copying length bytes from offset in the old stream, merged into the last
+// operation if that copies the range just before it.
+func (delta *Delta) AddCopy(offset int64, length int64) {
+	if n := len(*delta); n > 0 && (*delta)[n-1].Type == OpCopy && (*delta)[n-1].Offset+(*delta)[n-1].Length == offset {
+		(*delta)[n-1].Length += length
+		return
+	}
+	*delta = append(*delta, Op{Type: OpCopy, Offset: offset, Length: length})
+}
+
+// AddLiteral appends an operation inserting a copy of data, merged into the last operation if that is
+// a literal too.
+func (delta *Delta) AddLiteral(data ...byte) {
+	if len(data) == 0 {
+		return
+	}
+	if n := len(*delta); n > 0 && (*delta)[n-1].Type == OpLiteral {
+		(*delta)[n-1].Data = append((*delta)[n-1].Data, data...)
+		return
+	}
+	*delta = append(*delta, Op{Type: OpLiteral, Data: bytes.Clone(data)})
+}
@@ -0,0 +1,177 @@
+package delta
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+	"github.com/kagalle/go-hashbuffer/signature"
+)
+
+// Make sure a delta between any two of the test files rebuilds the new file.
+func TestRoundTripFiles(t *testing.T) {
+	files := []string{"data_0", "data_1", "data_15", "data_16", "data_17", "data_1023", "data_1024", "data_1025", "data_long"}
+	for _, oldFile := range files {
+		for _, newFile := range files {
+			old := readTestData(t, "../testdata/"+oldFile)
+			hb, err := hashbuffer.NewFileHashBuffer("../testdata/"+newFile, 1024, 16)
+			check(t, err)
+			testRoundTrip(t, fmt.Sprintf("TestRoundTripFiles_%s_%s", oldFile, newFile), old, readTestData(t, "../testdata/"+newFile), hb, 16)
+		}
+	}
+}
+
+// Make sure edits to the data are found, and everything else is copied.
+func TestRoundTripEdits(t *testing.T) {
+	const blockSize = 64
+
+	old := readTestData(t, "../testdata/data_long")
+	edits := map[string][]byte{
+		"identical": old,
+		"insert":    concat(old[:1000], []byte("inserted text"), old[1000:]),
+		"delete":    concat(old[:1000], old[1500:]),
+		"modify":    concat(old[:20000], []byte("XXXX"), old[20004:]),
+		"prepend":   concat([]byte("x"), old),
+		"truncate":  old[:len(old)-7],
+		"append":    concat(old, []byte("appended")),
+		"reorder":   concat(old[20000:], old[:20000]),
+	}
+	for name, edited := range edits {
+		title := "TestRoundTripEdits_" + name
+		hb, err := hashbuffer.NewBytesHashBuffer(edited, blockSize)
+		check(t, err)
+		delta := testRoundTrip(t, title, old, edited, hb, blockSize)
+		literal := 0
+		for _, op := range delta {
+			if op.Type == OpLiteral {
+				literal += len(op.Data)
+			}
+		}
+		// at most the edit itself and about a block on either side of it
+		if literal > 3*blockSize+len("inserted text") {
+			t.Errorf("Error %s: %d literal bytes", title, literal)
+		}
+	}
+	hb, err := hashbuffer.NewBytesHashBuffer(old, blockSize)
+	check(t, err)
+	delta := testRoundTrip(t, "TestRoundTripEdits_single", old, old, hb, blockSize)
+	if len(delta) != 1 || delta[0].Type != OpCopy || delta[0].Offset != 0 || delta[0].Length != int64(len(old)) {
+		t.Errorf("Error TestRoundTripEdits_single: got %v for identical data", delta)
+	}
+}
+
+// Make sure a mismatched window size or strong hash is rejected.
+func TestComputeMismatched(t *testing.T) {
+	sig := generate(t, readTestData(t, "../testdata/data_long"), 16)
+	hb, err := hashbuffer.NewFileHashBuffer("../testdata/data_long", 1024, 32)
+	check(t, err)
+	defer hb.Close()
+	if _, err = Compute(hb, sig, md5.New); !errors.Is(err, ErrMismatchedSignature) {
+		t.Errorf("Error TestComputeMismatched: got %v for the wrong window size", err)
+	}
+	short, err := hashbuffer.NewFileHashBuffer("../testdata/data_long", 1024, 8)
+	check(t, err)
+	defer short.Close()
+	if _, err = Compute(short, sig, md5.New); !errors.Is(err, ErrMismatchedSignature) {
+		t.Errorf("Error TestComputeMismatched: got %v for a window smaller than the block", err)
+	}
+	sig.StrongSize = 20
+	if _, err = Compute(hb, sig, md5.New); !errors.Is(err, ErrMismatchedSignature) {
+		t.Errorf("Error TestComputeMismatched: got %v for the wrong strong hash", err)
+	}
+}
+
+// Make sure invalid encodings and copies past the end of the old data are rejected.
+func TestInvalidDelta(t *testing.T) {
+	data, err := Delta{{Type: OpLiteral, Data: []byte("abc")}, {Type: OpCopy, Offset: 10, Length: 20}}.MarshalBinary()
+	check(t, err)
+	var delta Delta
+	if err = delta.UnmarshalBinary(data[:len(data)-1]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error TestInvalidDelta: got %v for truncated data", err)
+	}
+	if err = delta.UnmarshalBinary(append(bytes.Clone(data[:len(data)-1]), 9)); !errors.Is(err, ErrInvalidDelta) {
+		t.Errorf("Error TestInvalidDelta: got %v for a bad op", err)
+	}
+	check(t, delta.UnmarshalBinary(data))
+	if err = Apply(bytes.NewReader(make([]byte, 25)), delta, io.Discard); !errors.Is(err, ErrInvalidDelta) {
+		t.Errorf("Error TestInvalidDelta: got %v for a copy past the end", err)
+	}
+}
+
+// Make sure AddCopy and AddLiteral merge contiguous copies and adjacent literals, and copy the literal data.
+func TestDeltaAdd(t *testing.T) {
+	data := []byte("ab")
+	var delta Delta
+	delta.AddLiteral()
+	delta.AddCopy(0, 10)
+	delta.AddCopy(10, 5)
+	delta.AddCopy(20, 5)
+	delta.AddLiteral(data...)
+	delta.AddLiteral('c')
+	delta.AddCopy(25, 1)
+	data[0] = 'x'
+	want := Delta{{Type: OpCopy, Offset: 0, Length: 15}, {Type: OpCopy, Offset: 20, Length: 5},
+		{Type: OpLiteral, Data: []byte("abc")}, {Type: OpCopy, Offset: 25, Length: 1}}
+	if fmt.Sprint(delta) != fmt.Sprint(want) {
+		t.Errorf("Error TestDeltaAdd: got %v, want %v", delta, want)
+	}
+}
+
+func testRoundTrip(t *testing.T, title string, old []byte, want []byte, hb hashbuffer.HashBuffer, blockSize int) Delta {
+	t.Helper()
+	defer hb.Close()
+	sig := generate(t, old, blockSize)
+	delta, err := Compute(hb, sig, md5.New)
+	check(t, err)
+	// encode and decode before applying, as if it had been sent elsewhere
+	data, err := delta.MarshalBinary()
+	check(t, err)
+	var decoded Delta
+	check(t, decoded.UnmarshalBinary(data))
+	var rebuilt bytes.Buffer
+	check(t, Apply(bytes.NewReader(old), decoded, &rebuilt))
+	if !bytes.Equal(rebuilt.Bytes(), want) {
+		t.Fatalf("Error %s: rebuilt %d bytes that do not match the %d expected", title, rebuilt.Len(), len(want))
+	}
+	for i := 1; i < len(delta); i++ {
+		if delta[i].Type == OpLiteral && delta[i-1].Type == OpLiteral {
+			t.Errorf("Error %s: adjacent literals were not merged", title)
+		}
+	}
+	return delta
+}
+
+func generate(t *testing.T, old []byte, blockSize int) *signature.Signature {
+	t.Helper()
+	hb, err := hashbuffer.NewBytesHashBuffer(old, blockSize)
+	check(t, err)
+	sig, err := signature.GenerateSignature(hb, blockSize, md5.New)
+	check(t, err)
+	return sig
+}
+
+func concat(parts ...[]byte) (result []byte) {
+	for _, part := range parts {
+		result = append(result, part...)
+	}
+	return
+}
+
+func readTestData(t *testing.T, filename string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	check(t, err)
+	return data
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Error %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package delta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// The binary encoding:
+//
+//	magic    4 bytes  "HBDL"
+//	version  1 byte   1
+//	ops      for each op, its type byte, then
+//	           OpCopy:    offset uvarint, length uvarint
+//	           OpLiteral: length uvarint, then the data
+//	end      1 byte   0
+const (
+	magic   = "HBDL"
+	version = 1
+	opEnd   = 0
+)
+
+// MarshalBinary encodes the delta so it can be stored or exchanged.
+func (delta Delta) MarshalBinary() (data []byte, err error) {
+	var buffer bytes.Buffer
+	_, err = delta.WriteTo(&buffer)
+	data = buffer.Bytes()
+	return
+}
+
+// UnmarshalBinary decodes a delta encoded by MarshalBinary.
+func (delta *Delta) UnmarshalBinary(data []byte) (err error) {
+	reader := bytes.NewReader(data)
+	_, err = delta.ReadFrom(reader)
+	if err == nil && reader.Len() != 0 {
+		err = ErrInvalidDelta
+	}
+	return
+}
+
+// WriteTo writes the encoded delta to writer.
+func (delta Delta) WriteTo(writer io.Writer) (n int64, err error) {
+	counting := &countingWriter{writer: writer}
+	buffered := bufio.NewWriter(counting)
+	buffered.WriteString(magic)
+	buffered.WriteByte(version)
+	for _, op := range delta {
+		switch op.Type {
+		case OpCopy:
+			if op.Offset < 0 || op.Length < 0 {
+				err = ErrInvalidDelta
+				n = counting.n
+				return
+			}
+			buffered.WriteByte(byte(OpCopy))
+			buffered.Write(binary.AppendUvarint(nil, uint64(op.Offset)))
+			buffered.Write(binary.AppendUvarint(nil, uint64(op.Length)))
+		case OpLiteral:
+			buffered.WriteByte(byte(OpLiteral))
+			buffered.Write(binary.AppendUvarint(nil, uint64(len(op.Data))))
+			buffered.Write(op.Data)
+		default:
+			err = ErrInvalidDelta
+			n = counting.n
+			return
+		}
+	}
+	buffered.WriteByte(opEnd)
+	// bufio.Writer keeps the first error, so checking once is enough
+	err = buffered.Flush()
+	n = counting.n
+	return
+}
+
+// ReadFrom reads an encoded delta from reader, replacing the contents of delta.
+// It reads exactly the bytes of the delta, so more data may follow it in the stream.
+func (delta *Delta) ReadFrom(reader io.Reader) (n int64, err error) {
+	counting := &countingReader{reader: reader}
+	defer func() {
+		n = counting.n
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+	header := make([]byte, len(magic)+1)
+	if _, err = io.ReadFull(counting, header); err != nil {
+		return
+	}
+	if string(header[:len(magic)]) != magic || header[len(magic)] != version {
+		err = ErrInvalidDelta
+		return
+	}
+	var decoded Delta
+	for {
+		var opType byte
+		if opType, err = counting.ReadByte(); err != nil {
+			return
+		}
+		switch OpType(opType) {
+		case opEnd:
+			*delta = decoded
+			return
+		case OpCopy:
+			var offset, length uint64
+			if offset, err = binary.ReadUvarint(counting); err != nil {
+				return
+			}
+			if length, err = binary.ReadUvarint(counting); err != nil {
+				return
+			}
+			if offset > 1<<62 || length > 1<<62 {
+				err = ErrInvalidDelta
+				return
+			}
+			decoded = append(decoded, Op{Type: OpCopy, Offset: int64(offset), Length: int64(length)})
+		case OpLiteral:
+			var length uint64
+			if length, err = binary.ReadUvarint(counting); err != nil {
+				return
+			}
+			// read through a limited reader so a corrupt length cannot allocate more than is there
+			var data bytes.Buffer
+			var read int64
+			if read, err = io.Copy(&data, io.LimitReader(counting, int64(min(length, 1<<62)))); err != nil {
+				return
+			}
+			if uint64(read) != length {
+				err = io.ErrUnexpectedEOF
+				return
+			}
+			decoded = append(decoded, Op{Type: OpLiteral, Data: data.Bytes()})
+		default:
+			err = ErrInvalidDelta
+			return
+		}
+	}
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	writer io.Writer
+	n      int64
+}
+
+func (w *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = w.writer.Write(p)
+	w.n += int64(n)
+	return
+}
+
+// countingReader counts the bytes read through it, reading one byte at a time for ReadByte
+// so nothing past the end of the delta is consumed.
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.n += int64(n)
+	return
+}
+
+func (r *countingReader) ReadByte() (b byte, err error) {
+	var one [1]byte
+	_, err = io.ReadFull(r, one[:])
+	b = one[0]
+	return
+}
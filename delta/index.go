@@ -0,0 +1,54 @@
+package delta
+
+import (
+	"bytes"
+	"hash"
+
+	"github.com/kagalle/go-hashbuffer/signature"
+)
+
+// Index finds the blocks of a signature with a given weak checksum, confirming each with its strong hash.
+type Index struct {
+	blocks []signature.Block
+	strong hash.Hash
+	// length the strong hashes of the blocks are truncated to
+	strongSize int
+	// block indexes, by weak checksum
+	byWeak map[uint32][]int
+}
+
+// NewIndex indexes blocks, whose strong hashes are those of strong, truncated to strongSize bytes.
+func NewIndex(blocks []signature.Block, strong hash.Hash, strongSize int) *Index {
+	index := &Index{blocks: blocks, strong: strong, strongSize: strongSize, byWeak: make(map[uint32][]int)}
+	for i, block := range blocks {
+		index.byWeak[block.Weak] = append(index.byWeak[block.Weak], i)
+	}
+	return index
+}
+
+// Match returns the index of the first block equal to data, which has the weak checksum given.
+func (index *Index) Match(data []byte, weak uint32) (block int, ok bool) {
+	candidates := index.byWeak[weak]
+	if len(candidates) == 0 {
+		return
+	}
+	sum := index.sum(data)
+	for _, block = range candidates {
+		if bytes.Equal(sum, index.blocks[block].Strong) {
+			ok = true
+			return
+		}
+	}
+	return
+}
+
+// Equal reports whether data has the strong hash of block.
+func (index *Index) Equal(data []byte, block int) bool {
+	return bytes.Equal(index.sum(data), index.blocks[block].Strong)
+}
+
+func (index *Index) sum(data []byte) []byte {
+	index.strong.Reset()
+	index.strong.Write(data)
+	return index.strong.Sum(nil)[:index.strongSize]
+}
@@ -0,0 +1,35 @@
+package hashbuffer
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrClosed is returned when a HashBuffer is used after it has been closed.
+	ErrClosed = errors.New("hashbuffer: closed")
+	// ErrInvalidWindowSize is returned when a HashBuffer is created with a window size that is not positive.
+	ErrInvalidWindowSize = errors.New("hashbuffer: window size must be positive")
+	// ErrInvalidBufferSize is returned when a HashBuffer is created with a buffer size that is not positive,
+	// or (with WithBufferSize) not larger than the window size.
+	ErrInvalidBufferSize = errors.New("hashbuffer: buffer size must be larger than the window size")
+	// ErrInvalidStride is returned when a HashBuffer is created with a stride that is not positive.
+	ErrInvalidStride = errors.New("hashbuffer: stride must be positive")
+	// ErrInvalidOption is returned when an option does not apply to the kind of HashBuffer being created.
+	ErrInvalidOption = errors.New("hashbuffer: option does not apply")
+)
+
+// ReadError is returned when reading the stream fails, with the stream offset of the first byte the
+// failed read would have returned.  Use errors.As to get it, or errors.Is to test the underlying error.
+type ReadError struct {
+	Offset int64
+	Err    error
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("hashbuffer: read at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
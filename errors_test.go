@@ -0,0 +1,111 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+var errTestRead = errors.New("test read error")
+
+// Make sure a read error is returned as a *ReadError with the offset it happened at, wrapping the reader's error.
+func TestReadError(t *testing.T) {
+	for _, size := range []int{0, 100, 1025} {
+		title := fmt.Sprintf("TestReadError_%d", size)
+		reader := io.MultiReader(bytes.NewReader(testData[:size]), iotest.ErrReader(errTestRead))
+		hb, err := NewReaderHashBuffer(reader, 64, 16)
+		check(t, err)
+		windows := 0
+		for {
+			var window []byte
+			window, err = hb.GetWindow()
+			if err != nil || len(window) == 0 {
+				break
+			}
+			windows++
+		}
+		if !errors.Is(err, errTestRead) {
+			t.Fatalf("Error %s: got %v, want the reader's error", title, err)
+		}
+		var readError *ReadError
+		if !errors.As(err, &readError) || readError.Offset != int64(size) {
+			t.Errorf("Error %s: got %#v, want a *ReadError at offset %d", title, err, size)
+		}
+		// every full window before the error is returned
+		if want := max(size-15, 0); windows != want {
+			t.Errorf("Error %s: got %d windows before the error, want %d", title, windows, want)
+		}
+		hb.Close()
+	}
+}
+
+// Make sure Skip() returns read errors the same way.
+func TestReadErrorSkip(t *testing.T) {
+	const title = "TestReadErrorSkip"
+
+	reader := io.MultiReader(bytes.NewReader(testData[:1000]), iotest.ErrReader(errTestRead))
+	hb, err := NewReaderHashBuffer(reader, 64, 16)
+	check(t, err)
+	defer hb.Close()
+	_, err = hb.Skip(2000)
+	var readError *ReadError
+	if !errors.As(err, &readError) || !errors.Is(err, errTestRead) || readError.Offset != 1000 {
+		t.Errorf("Error %s: got %v, want a *ReadError at offset 1000", title, err)
+	}
+}
+
+// Make sure data returned along with an error, as io.Reader allows, is not lost.
+func TestReadDataWithError(t *testing.T) {
+	const title = "TestReadDataWithError"
+
+	for _, size := range []int{1, 15, 16, 1025} {
+		hb, err := NewReaderHashBuffer(iotest.DataErrReader(bytes.NewReader(testData[:size])), 1024, 16)
+		check(t, err)
+		var got []byte
+		for window, err := range hb.Windows() {
+			check(t, err)
+			if got == nil {
+				got = append(got, window...)
+			} else {
+				got = append(got, window[len(window)-1])
+			}
+		}
+		if !bytes.Equal(got, testData[:size]) {
+			t.Errorf("Error %s: read %d bytes of %d", title, len(got), size)
+		}
+		hb.Close()
+	}
+}
+
+// Make sure sizes that are not positive are rejected by each constructor.
+func TestInvalidSizes(t *testing.T) {
+	const title = "TestInvalidSizes"
+
+	for _, sizes := range []struct {
+		bufferSize int
+		windowSize int
+		want       error
+	}{
+		{1024, 0, ErrInvalidWindowSize},
+		{1024, -1, ErrInvalidWindowSize},
+		{0, 16, ErrInvalidBufferSize},
+		{-1, 16, ErrInvalidBufferSize},
+	} {
+		var hbs [3]HashBuffer
+		var errs [3]error
+		hbs[0], errs[0] = NewFileHashBuffer("./testdata/data_long", sizes.bufferSize, sizes.windowSize)
+		hbs[1], errs[1] = NewReaderHashBuffer(bytes.NewReader(testData), sizes.bufferSize, sizes.windowSize)
+		hbs[2], errs[2] = NewMmapHashBuffer("./testdata/data_long", sizes.bufferSize, sizes.windowSize)
+		for i := range hbs {
+			if !errors.Is(errs[i], sizes.want) || hbs[i] != nil {
+				t.Errorf("Error %s: constructor %d got %v for buffer size %d and window size %d", title, i, errs[i], sizes.bufferSize, sizes.windowSize)
+			}
+		}
+	}
+	if hb, err := NewBytesHashBuffer(testData, 0); !errors.Is(err, ErrInvalidWindowSize) || hb != nil {
+		t.Errorf("Error %s: got %v from NewBytesHashBuffer for window size 0", title, err)
+	}
+}
@@ -11,16 +11,32 @@ type fileHashBuffer struct {
 }
 
 // NewFileHashBuffer creates a FileHashBuffer against the specified filespec, with the specified buffersize.
+// Returns ErrInvalidWindowSize or ErrInvalidBufferSize if either size is not positive; a buffer no larger
+// than the window is enlarged to twice the window size.
 func NewFileHashBuffer(filespec string, bufferSize int, windowSize int) (hashBuffer HashBuffer, err error) {
-	fhb := new(fileHashBuffer)
-	hashBuffer = fhb
-	fhb.abstractHashBuffer = new(abstractHashBuffer)
+	return OpenFile(filespec, positionalOptions(bufferSize, windowSize)...)
+}
 
+// OpenFile creates a FileHashBuffer against the specified filespec, configured by options; WithWindowSize is required.
+func OpenFile(filespec string, options ...Option) (hashBuffer HashBuffer, err error) {
+	o, err := newOptions(options, false)
+	if err != nil {
+		return
+	}
 	f, err := os.Open(filespec) // f : *os.File which implements io.Reader
 	if err != nil {
 		return
 	}
-	fhb.abstractHashBuffer.isOpen = true
-	fhb.abstractHashBuffer.init(f, f, bufferSize, windowSize)
+	hashBuffer = newFileHashBuffer(f, o)
 	return
 }
+
+// newFileHashBuffer creates a FileHashBuffer against an already opened file.
+func newFileHashBuffer(f *os.File, o *options) *fileHashBuffer {
+	fhb := new(fileHashBuffer)
+	fhb.abstractHashBuffer = new(abstractHashBuffer)
+	fhb.file = f
+	fhb.abstractHashBuffer.isOpen = true
+	fhb.abstractHashBuffer.init(f, f, o)
+	return fhb
+}
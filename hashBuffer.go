@@ -1,33 +1,78 @@
 package hashbuffer
 
 import (
-	"testing"
+	"iter"
+	"log/slog"
 )
 
 /*
  * Implementations:
  * 	fileHashBuffer.go :
  *		NewFileHashBuffer(filespec string, bufferSize int, windowSize int) (hashBuffer HashBuffer, err error)
+ * 	readerHashBuffer.go :
+ *		NewReaderHashBuffer(reader io.Reader, bufferSize int, windowSize int) (hashBuffer HashBuffer, err error)
+ * 	bytesHashBuffer.go :
+ *		NewBytesHashBuffer(data []byte, windowSize int) (hashBuffer HashBuffer, err error)
+ * 	mmapHashBuffer.go :
+ *		NewMmapHashBuffer(filespec string, bufferSize int, windowSize int) (hashBuffer HashBuffer, err error)
+ *
+ * Each also has a constructor configured with Options (options.go):
+ *		OpenFile(filespec string, options ...Option) (hashBuffer HashBuffer, err error)
+ *		NewReader(reader io.Reader, options ...Option) (hashBuffer HashBuffer, err error)
+ *		NewBytes(data []byte, options ...Option) (hashBuffer HashBuffer, err error)
+ *		OpenMmap(filespec string, options ...Option) (hashBuffer HashBuffer, err error)
  *
  */
 
 // HashBuffer defines method to retrieve one or multiple bytes from a buffered stream of data.
 type HashBuffer interface {
-	// Get one window of data; each call moves data forward by one byte.
+	// Get one window of data; each call moves data forward by one byte (or the stride set with WithStride).
 	// Param []byte: buffer of window
-	// Param error: non-nil if an error occurred trying to read (something other than EOF).
+	// Param error: non-nil if an error occurred trying to read (something other than EOF); a *ReadError.
 	GetWindow() (window []byte, err error)
+	// Get the window `stride` bytes after the previous one, whatever the stride set with WithStride;
+	// with stride == window size, consecutive calls return non-overlapping blocks.
+	GetWindowStride(stride int) (window []byte, err error)
+	// Get the windows of each of the sizes set with WithWindowSizes, aligned as set with WithAlignment,
+	// moving forward as GetWindow() does; without WithWindowSizes, it is the one window of GetWindow().
+	// At the edges of the stream, the windows that do not fit are nil.
+	// The slice returned is only valid until the next call.
+	GetWindows() (windows [][]byte, err error)
 	// Get next available byte of data; push this byte into the window.
 	// This is equivelant to calling GetWindow() and using the right-most byte returned.
 	// This is meant for rolling-hash algorithms that take an initial buffer of data and
 	// then additional bytes are added in.
 	GetNext() (nextByte byte, byteAvailable bool, err error)
+	// Get the next available byte of data along with the byte that leaves the window as it slides forward.
+	// The outgoing byte is the first byte of the window returned by the previous call.
+	// This is meant for rolling-hash algorithms (e.g. Rabin-Karp, Buzhash) that need both ends of the slide.
+	GetRoll() (in byte, out byte, ok bool, err error)
 	// Skip over the next `count` bytes in the input stream.  This is equivelant to calling
 	// `GetNext()` `count` times, and discarding the results.
 	// Returns the number actually skipped (less than `count` if EOF is reached).
 	Skip(count int) (numberSkipped int, err error)
-	// Close the file handle
+	// Get up to `n` bytes from the start of the current window (see Offset()), or of the stream before the
+	// first window, without moving forward; the buffer grows if `n` is larger.  Fewer bytes are returned
+	// only at the end of the stream, or along with a read error.  The data is only valid until the next call.
+	Peek(n int) (data []byte, err error)
+	// Iterate over the remaining windows, as returned by repeated calls to GetWindow().
+	// The window is only valid until the next iteration; a read error is yielded once, with a nil window.
+	Windows() iter.Seq2[[]byte, error]
+	// Iterate over the remaining windows, with each window starting `stride` bytes after the previous one.
+	WindowsStride(stride int) iter.Seq2[[]byte, error]
+	// Iterate over the remaining incoming bytes, as returned by repeated calls to GetNext().
+	Bytes() iter.Seq2[byte, error]
+	// Stream offset of the start of the current window: the window most recently returned,
+	// or the one that would have been returned last if Skip() had been GetNext() calls; -1 before the first.
+	Offset() int64
+	// Number of bytes read from the stream so far (read ahead of the window, so it may exceed Offset()+window size).
+	TotalRead() int64
+	// Close the file handle, or other source, and release the buffer; the first call returns any error
+	// closing it, and later calls do nothing.  Other methods then return ErrClosed.
 	Close() (err error)
-	// Send testing object in to which HashBuffer will write information on its progress
-	SetTesting(t *testing.T)
+	// Send logger in to which HashBuffer will write information on its progress; nil disables logging.
+	// Per-byte progress is logged at LevelTrace, buffer fills at slog.LevelDebug and read errors at slog.LevelWarn.
+	SetLogger(logger *slog.Logger)
+	// Send testing object (normally a *testing.T) in to which HashBuffer will write information on its progress
+	SetTesting(t TestingT)
 }
@@ -0,0 +1,71 @@
+package hashbuffer
+
+import (
+	"iter"
+)
+
+// Windows returns an iterator over the remaining windows, each obtained with GetWindow() (so a stride set with WithStride applies).
+// Iteration ends at the end of the stream, or after yielding a nil window with a non-nil error.
+// The window is only valid until the next iteration.  Breaking out of the loop leaves the HashBuffer
+// positioned just after the last window yielded, so it can still be used (and must still be closed).
+func (ahb *abstractHashBuffer) Windows() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		for {
+			window, err := ahb.GetWindow()
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if len(window) == 0 || !yield(window, nil) {
+				return
+			}
+		}
+	}
+}
+
+// WindowsStride is like Windows, but each window starts stride bytes after the previous one;
+// a stride equal to the window size gives non-overlapping blocks.  A stride less than 1 is treated as 1.
+// Iteration ends when there is no full window a stride away from the previous one.
+// The stride given replaces any set with WithStride.
+func (ahb *abstractHashBuffer) WindowsStride(stride int) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		for first := true; ; first = false {
+			if !first && stride > 1 {
+				ok, err := ahb.advance(stride - 1)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				// Skip stops at the last full window, which is not a stride away from the previous one
+				if !ok {
+					return
+				}
+			}
+			window, err := ahb.nextWindow()
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if len(window) == 0 || !yield(window, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Bytes returns an iterator over the remaining incoming bytes, each obtained with GetNext().
+// Iteration ends at the end of the stream, or after yielding a non-nil error.
+func (ahb *abstractHashBuffer) Bytes() iter.Seq2[byte, error] {
+	return func(yield func(byte, error) bool) {
+		for {
+			nextByte, ok, err := ahb.GetNext()
+			if err != nil {
+				yield(0, err)
+				return
+			}
+			if !ok || !yield(nextByte, nil) {
+				return
+			}
+		}
+	}
+}
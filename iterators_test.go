@@ -0,0 +1,118 @@
+package hashbuffer
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"testing/iotest"
+)
+
+// Make sure Windows() yields the same windows as calling GetWindow() until it returns an empty window.
+func TestWindows(t *testing.T) {
+	testWindows(t, "./testdata/data_0", "TestWindows_0", 0)
+	testWindows(t, "./testdata/data_15", "TestWindows_15", 15)
+	testWindows(t, "./testdata/data_17", "TestWindows_17", 17)
+	testWindows(t, "./testdata/data_1025", "TestWindows_1025", 1025)
+	testWindows(t, "./testdata/data_long", "TestWindows_long", 35539)
+}
+
+// Make sure WindowsStride() advances by the stride, including non-overlapping blocks.
+func TestWindowsStride(t *testing.T) {
+	const windowSize = 16
+
+	for _, stride := range []int{1, 4, windowSize, 1000} {
+		title := fmt.Sprintf("TestWindowsStride_%d", stride)
+		hb, err := NewFileHashBuffer("./testdata/data_long", 1024, windowSize)
+		check(t, err)
+		start, count := 0, 0
+		for window, err := range hb.WindowsStride(stride) {
+			check(t, err)
+			if !testEq(window, testData[start:start+windowSize]) {
+				t.Fatalf("Error %s: window at %d is %#x", title, start, window)
+			}
+			start += stride
+			count++
+		}
+		if want := (len(testData)-windowSize)/stride + 1; count != want {
+			t.Errorf("Error %s: got %d windows, want %d", title, count, want)
+		}
+		closeTestHashBuffer(t, hb)
+	}
+}
+
+// Make sure Bytes() yields each byte after the first window, and that breaking out early leaves the position intact.
+func TestBytes(t *testing.T) {
+	const title = "TestBytes"
+	const windowSize = 16
+
+	hb, err := NewFileHashBuffer("./testdata/data_1025", 1024, windowSize)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	testGet(t, hb, title, testData, 0)
+	i := windowSize
+	for nextByte, err := range hb.Bytes() {
+		check(t, err)
+		if nextByte != testData[i] {
+			t.Fatalf("Error %s: byte %d is %#x, want %#x", title, i, nextByte, testData[i])
+		}
+		i++
+		if i == 100 {
+			break
+		}
+	}
+	testGet(t, hb, title, testData, 100-windowSize+1)
+	for nextByte, err := range hb.Bytes() {
+		check(t, err)
+		i++
+		if nextByte != testData[i] {
+			t.Fatalf("Error %s: byte %d is %#x, want %#x", title, i, nextByte, testData[i])
+		}
+	}
+	if i != 1024 {
+		t.Errorf("Error %s: last byte %d, want 1024", title, i)
+	}
+}
+
+// Make sure a read error is yielded once and ends the iteration.
+func TestWindowsError(t *testing.T) {
+	errTest := errors.New("test error")
+	hb, err := NewReaderHashBuffer(iotest.ErrReader(errTest), 1024, 16)
+	check(t, err)
+	count := 0
+	for window, err := range hb.Windows() {
+		count++
+		if window != nil || !errors.Is(err, errTest) {
+			t.Errorf("Error TestWindowsError: got %#x, %v", window, err)
+		}
+	}
+	if count != 1 {
+		t.Errorf("Error TestWindowsError: yielded %d times, want 1", count)
+	}
+}
+
+func testWindows(t *testing.T, filename string, title string, expectedSize int) {
+	const windowSize = 16
+
+	t.Logf("start %s", title)
+	hb, err := NewFileHashBuffer(filename, 1024, windowSize)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	i := 0
+	for window, err := range hb.Windows() {
+		check(t, err)
+		if !testEq(window, testData[i:i+len(window)]) {
+			t.Fatalf("Error %s: window %d is %#x", title, i, window)
+		}
+		i++
+	}
+	want := expectedSize - windowSize + 1
+	if expectedSize < windowSize && expectedSize > 0 {
+		want = 1
+	}
+	if expectedSize == 0 {
+		want = 0
+	}
+	if i != want {
+		t.Errorf("Error %s: got %d windows, want %d", title, i, want)
+	}
+}
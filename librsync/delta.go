@@ -0,0 +1,270 @@
+package librsync
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+	"github.com/kagalle/go-hashbuffer/delta"
+)
+
+// The delta commands.  Integer parameters are 1, 2, 4 or 8 bytes, as selected by the command.
+const (
+	opEnd = 0x00
+	// opLiteral1 to opLiteral64 are followed by 1 to 64 bytes of literal data
+	opLiteral1  = 0x01
+	opLiteral64 = 0x40
+	// opLiteralN1 to opLiteralN8 are followed by the length of the literal data, then the data
+	opLiteralN1 = 0x41
+	opLiteralN8 = 0x44
+	// opCopyN1N1 to opCopyN8N8 are followed by the offset in the old file and the length to copy;
+	// each group of four has one size of offset, and the length sizes in turn
+	opCopyN1N1 = 0x45
+	opCopyN8N8 = 0x54
+)
+
+// ComputeDelta scans the remaining data in hb, which must have been created with a window size of the
+// signature's block size, for blocks of the old file described by sig.  Full blocks are found at any
+// offset; the last block, which may be short, is also found at the end of the new file.
+func ComputeDelta(hb hashbuffer.HashBuffer, sig *Signature) (d delta.Delta, err error) {
+	if !sig.Type.valid() || sig.BlockSize <= 0 || sig.StrongSize > sig.Type.StrongSize() {
+		err = ErrInvalidSignature
+		return
+	}
+	index := delta.NewIndex(sig.Blocks, sig.Type.newStrong(), sig.StrongSize)
+
+	window, ok, err := hashbuffer.FirstBlock(hb, sig.BlockSize)
+	if err != nil {
+		return
+	}
+	if !ok {
+		err = ErrInvalidSignature
+		return
+	}
+	if len(window) == 0 {
+		return
+	}
+	if len(window) < sig.BlockSize {
+		// the whole stream is shorter than a block
+		addRest(&d, sig, index, window)
+		return
+	}
+	rest, err := delta.Scan(hb, window, sig.Type.newWeak(),
+		func(window []byte, sum uint64) (next int64, ok bool, err error) {
+			var block int
+			if block, ok = index.Match(window, uint32(sum)); ok {
+				d.AddCopy(int64(block)*int64(sig.BlockSize), int64(sig.BlockSize))
+				next = hb.Offset() + int64(sig.BlockSize)
+			}
+			return
+		}, func(out byte) { d.AddLiteral(out) })
+	if err != nil {
+		return
+	}
+	addRest(&d, sig, index, rest)
+	return
+}
+
+// addRest adds the data at the end of the new file, which may end with the old file's last block.  The
+// signature does not record the length of that block, so each shorter end of rest is tried in turn.
+func addRest(d *delta.Delta, sig *Signature, index *delta.Index, rest []byte) {
+	last := len(sig.Blocks) - 1
+	if last >= 0 {
+		start := max(len(rest)-sig.BlockSize, 0)
+		weak := sig.Type.newWeak()
+		weak.update(rest[start:])
+		for ; start < len(rest); start++ {
+			if weak.digest() == sig.Blocks[last].Weak && index.Equal(rest[start:], last) {
+				d.AddLiteral(rest[:start]...)
+				d.AddCopy(int64(last)*int64(sig.BlockSize), int64(len(rest)-start))
+				return
+			}
+			weak.rollout(rest[start])
+		}
+	}
+	d.AddLiteral(rest...)
+}
+
+// WriteDelta writes d to writer as a librsync delta file, using the smallest command for each operation.
+func WriteDelta(writer io.Writer, d delta.Delta) (n int64, err error) {
+	buffered := bufio.NewWriter(writer)
+	command := binary.BigEndian.AppendUint32(make([]byte, 0, 17), DeltaMagic)
+	for _, op := range d {
+		switch op.Type {
+		case delta.OpCopy:
+			if op.Offset < 0 || op.Length <= 0 {
+				err = ErrInvalidDelta
+				return
+			}
+			offsetSize, offsetIndex := intSize(uint64(op.Offset))
+			lengthSize, lengthIndex := intSize(uint64(op.Length))
+			command = append(command, byte(opCopyN1N1+4*offsetIndex+lengthIndex))
+			command = appendInt(command, uint64(op.Offset), offsetSize)
+			command = appendInt(command, uint64(op.Length), lengthSize)
+		case delta.OpLiteral:
+			if len(op.Data) == 0 {
+				continue
+			}
+			if len(op.Data) <= opLiteral64 {
+				command = append(command, byte(opLiteral1+len(op.Data)-1))
+			} else {
+				size, index := intSize(uint64(len(op.Data)))
+				command = append(command, byte(opLiteralN1+index))
+				command = appendInt(command, uint64(len(op.Data)), size)
+			}
+		default:
+			err = ErrInvalidDelta
+			return
+		}
+		var written int
+		written, err = buffered.Write(command)
+		n += int64(written)
+		if err != nil {
+			return
+		}
+		command = command[:0]
+		if op.Type == delta.OpLiteral {
+			written, err = buffered.Write(op.Data)
+			n += int64(written)
+			if err != nil {
+				return
+			}
+		}
+	}
+	written, err := buffered.Write(append(command, opEnd))
+	n += int64(written)
+	if err != nil {
+		return
+	}
+	err = buffered.Flush()
+	return
+}
+
+// ReadDelta reads a librsync delta file from reader, up to and including its end command.  Unless reader is
+// an io.ByteReader, it is buffered, so more of it may be read.
+func ReadDelta(reader io.Reader) (d delta.Delta, err error) {
+	err = readDelta(reader, func(op delta.Op, literal io.Reader) (err error) {
+		if op.Type == delta.OpLiteral {
+			// grow with the data actually read rather than trusting the length
+			var data bytes.Buffer
+			_, err = io.CopyN(&data, literal, op.Length)
+			op.Data = data.Bytes()
+			op.Length = 0
+		}
+		if err == nil {
+			d = append(d, op)
+		}
+		return
+	})
+	return
+}
+
+// Patch rebuilds the new file from the old file and the librsync delta file read from deltaReader, writing
+// it to out.  Literal data is streamed, so the delta is never held in memory.
+func Patch(old io.ReaderAt, deltaReader io.Reader, out io.Writer) (err error) {
+	return readDelta(deltaReader, func(op delta.Op, literal io.Reader) (err error) {
+		var copied int64
+		if op.Type == delta.OpLiteral {
+			copied, err = io.CopyN(out, literal, op.Length)
+			return
+		}
+		copied, err = io.Copy(out, io.NewSectionReader(old, op.Offset, op.Length))
+		if err == nil && copied != op.Length {
+			err = ErrInvalidDelta
+		}
+		return
+	})
+}
+
+// byteReader is read one command byte at a time.
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// readDelta reads the commands of a delta file, calling apply for each operation.  For a literal, the
+// Length of op is set, and apply must read that many bytes of data from literal.
+func readDelta(reader io.Reader, apply func(op delta.Op, literal io.Reader) error) (err error) {
+	buffered, ok := reader.(byteReader)
+	if !ok {
+		buffered = bufio.NewReader(reader)
+	}
+	magic := make([]byte, 4)
+	if _, err = io.ReadFull(buffered, magic); err != nil {
+		return unexpectedEOF(err)
+	}
+	if binary.BigEndian.Uint32(magic) != DeltaMagic {
+		return ErrInvalidDelta
+	}
+	for {
+		var command byte
+		command, err = buffered.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		var op delta.Op
+		switch {
+		case command == opEnd:
+			return
+		case command <= opLiteral64:
+			op = delta.Op{Type: delta.OpLiteral, Length: int64(command)}
+		case command <= opLiteralN8:
+			op = delta.Op{Type: delta.OpLiteral}
+			op.Length, err = readInt(buffered, 1<<(command-opLiteralN1))
+		case command <= opCopyN8N8:
+			op = delta.Op{Type: delta.OpCopy}
+			index := command - opCopyN1N1
+			op.Offset, err = readInt(buffered, 1<<(index/4))
+			if err == nil {
+				op.Length, err = readInt(buffered, 1<<(index%4))
+			}
+		default:
+			return ErrInvalidDelta
+		}
+		if err != nil {
+			return
+		}
+		err = apply(op, buffered)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+	}
+}
+
+// intSize returns the number of bytes, 1, 2, 4 or 8, needed for value, and its index in that list.
+func intSize(value uint64) (size int, index int) {
+	size = 1
+	for value >= 1<<(8*size) && size < 8 {
+		size *= 2
+		index++
+	}
+	return
+}
+
+func appendInt(data []byte, value uint64, size int) []byte {
+	for shift := 8 * (size - 1); shift >= 0; shift -= 8 {
+		data = append(data, byte(value>>shift))
+	}
+	return data
+}
+
+// readInt reads a big-endian integer of size bytes, which must fit in an int64.
+func readInt(reader io.Reader, size int) (value int64, err error) {
+	data := make([]byte, size)
+	if _, err = io.ReadFull(reader, data); err != nil {
+		err = unexpectedEOF(err)
+		return
+	}
+	var unsigned uint64
+	for _, b := range data {
+		unsigned = unsigned<<8 | uint64(b)
+	}
+	if unsigned > 1<<62 {
+		err = ErrInvalidDelta
+		return
+	}
+	value = int64(unsigned)
+	return
+}
@@ -0,0 +1,184 @@
+// Package librsync reads and writes the signature and delta formats of librsync and its rdiff tool, so
+// signatures and deltas can be exchanged with programs built on it.
+//
+// A signature file is a 4 byte magic number that selects the weak and strong checksums, the block length
+// and the strong sum length, followed by the weak checksum and truncated strong sum of each block.  A
+// delta file is a 4 byte magic number followed by a stream of commands that insert literal data or copy
+// a range of the old file, ending with an end command.  All integers are big-endian.
+package librsync
+
+import (
+	"errors"
+	"hash"
+
+	"github.com/kagalle/go-hashbuffer/rolling"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/md4"
+)
+
+// SignatureType is the magic number at the start of a signature file, which selects its checksums.
+type SignatureType uint32
+
+const (
+	// MD4Signature uses the rollsum weak checksum and MD4 strong sums, as written by older versions of librsync.
+	MD4Signature SignatureType = 0x72730136
+	// Blake2Signature uses the rollsum weak checksum and BLAKE2b strong sums.
+	Blake2Signature SignatureType = 0x72730137
+	// RabinKarpMD4Signature uses the RabinKarp weak checksum and MD4 strong sums.
+	RabinKarpMD4Signature SignatureType = 0x72730146
+	// RabinKarpBlake2Signature uses the RabinKarp weak checksum and BLAKE2b strong sums, the default of current versions of rdiff.
+	RabinKarpBlake2Signature SignatureType = 0x72730147
+)
+
+// DeltaMagic is the magic number at the start of a delta file.
+const DeltaMagic = 0x72730236
+
+// DefaultBlockSize is librsync's default block length, RS_DEFAULT_BLOCK_LEN.
+const DefaultBlockSize = 2048
+
+var (
+	// ErrInvalidSignature is returned for an unknown signature type, a block size that is not positive or
+	// does not match the HashBuffer's window size, a strong sum size too large for the type, or a
+	// malformed signature file.
+	ErrInvalidSignature = errors.New("librsync: invalid signature")
+	// ErrInvalidDelta is returned for a malformed delta file, or a copy outside the old file.
+	ErrInvalidDelta = errors.New("librsync: invalid delta")
+)
+
+// valid reports whether sigType is a known signature type.
+func (sigType SignatureType) valid() bool {
+	switch sigType {
+	case MD4Signature, Blake2Signature, RabinKarpMD4Signature, RabinKarpBlake2Signature:
+		return true
+	}
+	return false
+}
+
+// newStrong returns the strong hash of the signature type.
+func (sigType SignatureType) newStrong() hash.Hash {
+	if sigType == MD4Signature || sigType == RabinKarpMD4Signature {
+		return md4.New()
+	}
+	// only fails for a key that is too long
+	strong, _ := blake2b.New256(nil)
+	return strong
+}
+
+// StrongSize returns the full length of the strong sums of the signature type; signatures may store them truncated.
+func (sigType SignatureType) StrongSize() int {
+	if sigType == MD4Signature || sigType == RabinKarpMD4Signature {
+		return md4.Size
+	}
+	return blake2b.Size256
+}
+
+// newWeak returns the weak checksum of the signature type.
+func (sigType SignatureType) newWeak() weakSum {
+	if sigType == RabinKarpMD4Signature || sigType == RabinKarpBlake2Signature {
+		return newRabinKarp()
+	}
+	return new(rollsum)
+}
+
+// weakSum is a weak checksum that can be rolled over a window, and shrunk from the start of the window.
+// Sum64 is the digest.
+type weakSum interface {
+	rolling.RollingHasher
+	// update adds data to the end of the window.
+	update(data []byte)
+	// rollout removes out from the start of the window.
+	rollout(out byte)
+	digest() uint32
+}
+
+// rollsumCharOffset is added to each byte so that runs of zeros change the sum.
+const rollsumCharOffset = 31
+
+// rollsum is librsync's variant of the Adler checksum: two 16 bit sums without the modulus.
+type rollsum struct {
+	count uint32
+	s1    uint16
+	s2    uint16
+}
+
+func (sum *rollsum) update(data []byte) {
+	for _, b := range data {
+		sum.s1 += uint16(b) + rollsumCharOffset
+		sum.s2 += sum.s1
+	}
+	sum.count += uint32(len(data))
+}
+
+func (sum *rollsum) Reset(window []byte) {
+	*sum = rollsum{}
+	sum.update(window)
+}
+
+func (sum *rollsum) Roll(out, in byte) {
+	sum.s1 += uint16(in) - uint16(out)
+	sum.s2 += sum.s1 - uint16(sum.count)*(uint16(out)+rollsumCharOffset)
+}
+
+func (sum *rollsum) rollout(out byte) {
+	sum.s1 -= uint16(out) + rollsumCharOffset
+	sum.s2 -= uint16(sum.count) * (uint16(out) + rollsumCharOffset)
+	sum.count--
+}
+
+func (sum *rollsum) digest() uint32 {
+	return uint32(sum.s2)<<16 | uint32(sum.s1)
+}
+
+func (sum *rollsum) Sum64() uint64 {
+	return uint64(sum.digest())
+}
+
+const (
+	// the hash of an empty window
+	rabinKarpSeed = 1
+	rabinKarpMult = 0x08104225
+	// the inverse of rabinKarpMult modulo 2^32
+	rabinKarpInvMult = 0x98f009ad
+	// rabinKarpMult - 1, which accounts for the seed when a byte is removed
+	rabinKarpAdj = 0x08104224
+)
+
+// rabinKarp is librsync's RabinKarp polynomial hash modulo 2^32.
+type rabinKarp struct {
+	hash uint32
+	// rabinKarpMult to the power of the window length
+	mult uint32
+}
+
+func newRabinKarp() *rabinKarp {
+	return &rabinKarp{hash: rabinKarpSeed, mult: 1}
+}
+
+func (sum *rabinKarp) update(data []byte) {
+	for _, b := range data {
+		sum.hash = sum.hash*rabinKarpMult + uint32(b)
+		sum.mult *= rabinKarpMult
+	}
+}
+
+func (sum *rabinKarp) Reset(window []byte) {
+	*sum = *newRabinKarp()
+	sum.update(window)
+}
+
+func (sum *rabinKarp) Roll(out, in byte) {
+	sum.hash = sum.hash*rabinKarpMult + uint32(in) - sum.mult*(uint32(out)+rabinKarpAdj)
+}
+
+func (sum *rabinKarp) rollout(out byte) {
+	sum.mult *= rabinKarpInvMult
+	sum.hash -= sum.mult * (uint32(out) + rabinKarpAdj)
+}
+
+func (sum *rabinKarp) digest() uint32 {
+	return sum.hash
+}
+
+func (sum *rabinKarp) Sum64() uint64 {
+	return uint64(sum.hash)
+}
@@ -0,0 +1,250 @@
+package librsync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+	"github.com/kagalle/go-hashbuffer/delta"
+)
+
+// the signature types, with the golden files of data_long written with each; the golden files were
+// written by an independent implementation of the formats, following librsync's documentation and sources
+var goldenSignatures = []struct {
+	name       string
+	sigType    SignatureType
+	strongSize int
+}{
+	{"md4", MD4Signature, 16},
+	{"blake2", Blake2Signature, 32},
+	{"rk_md4", RabinKarpMD4Signature, 16},
+	{"rk_blake2_8", RabinKarpBlake2Signature, 8},
+}
+
+// Make sure signatures of data_long match the golden files byte for byte, and decode back to the same bytes.
+func TestSignatureGolden(t *testing.T) {
+	for _, golden := range goldenSignatures {
+		title := "TestSignatureGolden_" + golden.name
+		want := readTestData(t, "../testdata/librsync/data_long_"+golden.name+".sig")
+		hb, err := hashbuffer.NewFileHashBuffer("../testdata/data_long", 2048, 1000)
+		check(t, err)
+		sig, err := GenerateSignature(hb, golden.sigType, 1000, golden.strongSize)
+		check(t, err)
+		hb.Close()
+		data, err := sig.MarshalBinary()
+		check(t, err)
+		if !bytes.Equal(data, want) {
+			t.Errorf("Error %s: the signature does not match the golden file", title)
+		}
+		var decoded Signature
+		check(t, decoded.UnmarshalBinary(want))
+		data, err = decoded.MarshalBinary()
+		check(t, err)
+		if !bytes.Equal(data, want) || decoded.Type != golden.sigType || len(decoded.Blocks) != 36 {
+			t.Errorf("Error %s: decoding and encoding the golden file changed it", title)
+		}
+	}
+}
+
+// Make sure the golden delta, which uses each size of command, patches data_long, and is written back the same.
+func TestPatchGolden(t *testing.T) {
+	old := readTestData(t, "../testdata/data_long")
+	deltaData := readTestData(t, "../testdata/librsync/data_long.delta")
+	want := readTestData(t, "../testdata/librsync/data_long_patched")
+	var patched bytes.Buffer
+	check(t, Patch(bytes.NewReader(old), bytes.NewReader(deltaData), &patched))
+	if !bytes.Equal(patched.Bytes(), want) {
+		t.Errorf("Error TestPatchGolden: patched %d bytes that do not match the %d expected", patched.Len(), len(want))
+	}
+	d, err := ReadDelta(bytes.NewReader(deltaData))
+	check(t, err)
+	var written bytes.Buffer
+	n, err := WriteDelta(&written, d)
+	check(t, err)
+	if !bytes.Equal(written.Bytes(), deltaData) || n != int64(len(deltaData)) {
+		t.Errorf("Error TestPatchGolden: reading and writing the golden delta changed it")
+	}
+}
+
+// Make sure a delta against a golden signature, written by another implementation, rebuilds the new data.
+func TestDeltaGoldenSignature(t *testing.T) {
+	old := readTestData(t, "../testdata/data_long")
+	edited := concat(old[:1000], []byte("inserted text"), old[1000:20000], old[20500:])
+	for _, golden := range goldenSignatures {
+		var sig Signature
+		check(t, sig.UnmarshalBinary(readTestData(t, "../testdata/librsync/data_long_"+golden.name+".sig")))
+		hb, err := hashbuffer.NewBytesHashBuffer(edited, sig.BlockSize)
+		check(t, err)
+		d := testRoundTrip(t, "TestDeltaGoldenSignature_"+golden.name, &sig, old, edited, hb)
+		if literal := literalLength(d); literal > 3*sig.BlockSize {
+			t.Errorf("Error TestDeltaGoldenSignature_%s: %d literal bytes", golden.name, literal)
+		}
+	}
+}
+
+// Make sure a delta between any two of the test files rebuilds the new file, with each signature type.
+func TestRoundTripFiles(t *testing.T) {
+	files := []string{"data_0", "data_1", "data_15", "data_16", "data_17", "data_1023", "data_1024", "data_1025", "data_long"}
+	for _, golden := range goldenSignatures {
+		for _, oldFile := range files {
+			for _, newFile := range files {
+				old := readTestData(t, "../testdata/"+oldFile)
+				sig := generate(t, old, golden.sigType, 16, golden.strongSize)
+				hb, err := hashbuffer.NewFileHashBuffer("../testdata/"+newFile, 1024, 16)
+				check(t, err)
+				testRoundTrip(t, fmt.Sprintf("TestRoundTripFiles_%s_%s_%s", golden.name, oldFile, newFile),
+					sig, old, readTestData(t, "../testdata/"+newFile), hb)
+			}
+		}
+	}
+}
+
+// Make sure the short last block is found at the end of the new data, though the signature does not record its length.
+func TestRoundTripLastBlock(t *testing.T) {
+	old := readTestData(t, "../testdata/data_long")
+	edited := concat([]byte("prefix"), old[len(old)-539:])
+	for _, golden := range goldenSignatures {
+		sig := generate(t, old, golden.sigType, 1000, golden.strongSize)
+		hb, err := hashbuffer.NewBytesHashBuffer(edited, 1000)
+		check(t, err)
+		d := testRoundTrip(t, "TestRoundTripLastBlock_"+golden.name, sig, old, edited, hb)
+		if len(d) != 2 || d[1].Type != delta.OpCopy || d[1].Offset != 35000 || d[1].Length != 539 {
+			t.Errorf("Error TestRoundTripLastBlock_%s: got %v", golden.name, d)
+		}
+	}
+}
+
+// Make sure rolling the weak checksums, and shrinking them from the start, gives the checksum of the window.
+func TestWeakSums(t *testing.T) {
+	data := readTestData(t, "../testdata/data_long")
+	for _, golden := range goldenSignatures {
+		title := "TestWeakSums_" + golden.name
+		rolled := golden.sigType.newWeak()
+		rolled.update(data[:100])
+		for i := 1; i+100 <= 2000; i++ {
+			rolled.Roll(data[i-1], data[i+99])
+			fresh := golden.sigType.newWeak()
+			fresh.update(data[i : i+100])
+			if rolled.digest() != fresh.digest() {
+				t.Fatalf("Error %s: rolled to %d got %08x, want %08x", title, i, rolled.digest(), fresh.digest())
+			}
+		}
+		for i := 1901; i < 2000; i++ {
+			rolled.rollout(data[i-1])
+			fresh := golden.sigType.newWeak()
+			fresh.update(data[i:2000])
+			if rolled.digest() != fresh.digest() {
+				t.Fatalf("Error %s: shrunk to %d got %08x, want %08x", title, i, rolled.digest(), fresh.digest())
+			}
+		}
+	}
+}
+
+// Make sure malformed signatures and deltas, and copies past the end of the old data, are rejected.
+func TestInvalid(t *testing.T) {
+	sigData := readTestData(t, "../testdata/librsync/data_long_md4.sig")
+	var sig Signature
+	if err := sig.UnmarshalBinary(sigData[:len(sigData)-1]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error TestInvalid: got %v for a truncated signature", err)
+	}
+	if err := sig.UnmarshalBinary(append([]byte{0, 0, 0, 0}, sigData[4:]...)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Error TestInvalid: got %v for a bad signature magic", err)
+	}
+	hb, err := hashbuffer.NewFileHashBuffer("../testdata/data_long", 1024, 16)
+	check(t, err)
+	defer hb.Close()
+	if _, err = GenerateSignature(hb, MD4Signature, 16, 17); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Error TestInvalid: got %v for a strong size longer than MD4", err)
+	}
+	if _, err = GenerateSignature(hb, MD4Signature, 8, 0); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Error TestInvalid: got %v for block size 8 with window size 16", err)
+	}
+	short, err := hashbuffer.NewFileHashBuffer("../testdata/data_long", 1024, 8)
+	check(t, err)
+	defer short.Close()
+	if _, err = GenerateSignature(short, MD4Signature, 16, 0); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Error TestInvalid: got %v for block size 16 with window size 8", err)
+	}
+	sig16 := generate(t, readTestData(t, "../testdata/data_long"), MD4Signature, 16, 0)
+	if _, err = ComputeDelta(short, sig16); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Error TestInvalid: got %v for a delta with window size 8 against block size 16", err)
+	}
+
+	deltaData := readTestData(t, "../testdata/librsync/data_long.delta")
+	if _, err = ReadDelta(bytes.NewReader(deltaData[:len(deltaData)-1])); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error TestInvalid: got %v for a delta without an end command", err)
+	}
+	if _, err = ReadDelta(bytes.NewReader(deltaData[:8])); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error TestInvalid: got %v for a delta cut short in a literal", err)
+	}
+	if _, err = ReadDelta(bytes.NewReader(append(bytes.Clone(deltaData[:len(deltaData)-1]), 0x55))); !errors.Is(err, ErrInvalidDelta) {
+		t.Errorf("Error TestInvalid: got %v for a reserved command", err)
+	}
+	if err = Patch(bytes.NewReader(make([]byte, 100)), bytes.NewReader(deltaData), io.Discard); !errors.Is(err, ErrInvalidDelta) {
+		t.Errorf("Error TestInvalid: got %v for a copy past the end", err)
+	}
+}
+
+func testRoundTrip(t *testing.T, title string, sig *Signature, old []byte, want []byte, hb hashbuffer.HashBuffer) delta.Delta {
+	t.Helper()
+	defer hb.Close()
+	d, err := ComputeDelta(hb, sig)
+	check(t, err)
+	var deltaData bytes.Buffer
+	_, err = WriteDelta(&deltaData, d)
+	check(t, err)
+	var patched bytes.Buffer
+	check(t, Patch(bytes.NewReader(old), &deltaData, &patched))
+	if !bytes.Equal(patched.Bytes(), want) {
+		t.Fatalf("Error %s: patched %d bytes that do not match the %d expected", title, patched.Len(), len(want))
+	}
+	return d
+}
+
+func literalLength(d delta.Delta) (length int) {
+	for _, op := range d {
+		if op.Type == delta.OpLiteral {
+			length += len(op.Data)
+		}
+	}
+	return
+}
+
+func generate(t *testing.T, old []byte, sigType SignatureType, blockSize int, strongSize int) *Signature {
+	t.Helper()
+	hb, err := hashbuffer.NewBytesHashBuffer(old, blockSize)
+	check(t, err)
+	sig, err := GenerateSignature(hb, sigType, blockSize, strongSize)
+	check(t, err)
+	// as if it had been sent elsewhere
+	data, err := sig.MarshalBinary()
+	check(t, err)
+	var decoded Signature
+	check(t, decoded.UnmarshalBinary(data))
+	return &decoded
+}
+
+func concat(parts ...[]byte) (result []byte) {
+	for _, part := range parts {
+		result = append(result, part...)
+	}
+	return
+}
+
+func readTestData(t *testing.T, filename string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	check(t, err)
+	return data
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Error %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package librsync
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+	"github.com/kagalle/go-hashbuffer/signature"
+)
+
+// Signature is the content of a librsync signature file.
+type Signature struct {
+	// the magic number, which selects the checksums
+	Type SignatureType
+	// size of each block; the last block may be shorter
+	BlockSize int
+	// length of each strong sum, which may be truncated
+	StrongSize int
+	// one entry for each block, in stream order; unlike signature.Signature, the length of the last
+	// block is not recorded
+	Blocks []signature.Block
+}
+
+// magic, block size, strong size
+const signatureHeaderSize = 4 + 4 + 4
+
+// GenerateSignature computes the librsync signature of the remaining data in hb, which must have been
+// created with a window size of blockSize.  The strong sums are truncated to strongSize bytes, or kept
+// whole if strongSize is 0.
+func GenerateSignature(hb hashbuffer.HashBuffer, sigType SignatureType, blockSize int, strongSize int) (sig *Signature, err error) {
+	if strongSize == 0 {
+		strongSize = sigType.StrongSize()
+	}
+	if !sigType.valid() || blockSize <= 0 || strongSize < 0 || strongSize > sigType.StrongSize() {
+		err = ErrInvalidSignature
+		return
+	}
+	strong := sigType.newStrong()
+	sig = &Signature{Type: sigType, BlockSize: blockSize, StrongSize: strongSize}
+	addBlock := func(block []byte) {
+		weak := sigType.newWeak()
+		weak.update(block)
+		strong.Reset()
+		strong.Write(block)
+		sig.Blocks = append(sig.Blocks, signature.Block{Weak: weak.digest(), Strong: strong.Sum(nil)[:strongSize]})
+	}
+	ok, err := hashbuffer.ReadBlocks(hb, blockSize, addBlock)
+	if err == nil && !ok {
+		err = ErrInvalidSignature
+	}
+	return
+}
+
+// MarshalBinary encodes the signature as a librsync signature file.
+func (sig *Signature) MarshalBinary() (data []byte, err error) {
+	var buffer bytes.Buffer
+	_, err = sig.WriteTo(&buffer)
+	data = buffer.Bytes()
+	return
+}
+
+// UnmarshalBinary decodes a librsync signature file.
+func (sig *Signature) UnmarshalBinary(data []byte) (err error) {
+	_, err = sig.ReadFrom(bytes.NewReader(data))
+	return
+}
+
+// WriteTo writes the signature to writer as a librsync signature file.
+func (sig *Signature) WriteTo(writer io.Writer) (n int64, err error) {
+	if !sig.Type.valid() || sig.BlockSize <= 0 || sig.StrongSize < 0 || sig.StrongSize > sig.Type.StrongSize() {
+		err = ErrInvalidSignature
+		return
+	}
+	header := make([]byte, 0, signatureHeaderSize)
+	header = binary.BigEndian.AppendUint32(header, uint32(sig.Type))
+	header = binary.BigEndian.AppendUint32(header, uint32(sig.BlockSize))
+	header = binary.BigEndian.AppendUint32(header, uint32(sig.StrongSize))
+	buffered := bufio.NewWriter(writer)
+	written, err := buffered.Write(header)
+	n += int64(written)
+	if err != nil {
+		return
+	}
+	entry := make([]byte, 4+sig.StrongSize)
+	for _, block := range sig.Blocks {
+		if len(block.Strong) != sig.StrongSize {
+			err = ErrInvalidSignature
+			return
+		}
+		binary.BigEndian.PutUint32(entry, block.Weak)
+		copy(entry[4:], block.Strong)
+		written, err = buffered.Write(entry)
+		n += int64(written)
+		if err != nil {
+			return
+		}
+	}
+	err = buffered.Flush()
+	return
+}
+
+// ReadFrom reads a librsync signature file from reader, replacing the contents of sig.  The format does
+// not record the number of blocks, so it reads until the end of reader.
+func (sig *Signature) ReadFrom(reader io.Reader) (n int64, err error) {
+	header := make([]byte, signatureHeaderSize)
+	read, err := io.ReadFull(reader, header)
+	n += int64(read)
+	if err != nil {
+		err = unexpectedEOF(err)
+		return
+	}
+	sigType := SignatureType(binary.BigEndian.Uint32(header))
+	blockSize := binary.BigEndian.Uint32(header[4:])
+	strongSize := binary.BigEndian.Uint32(header[8:])
+	if !sigType.valid() || blockSize == 0 || blockSize > 1<<30 || strongSize > uint32(sigType.StrongSize()) {
+		err = ErrInvalidSignature
+		return
+	}
+	decoded := Signature{Type: sigType, BlockSize: int(blockSize), StrongSize: int(strongSize)}
+	entry := make([]byte, 4+strongSize)
+	for {
+		read, err = io.ReadFull(reader, entry)
+		n += int64(read)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return
+		}
+		decoded.Blocks = append(decoded.Blocks, signature.Block{
+			Weak:   binary.BigEndian.Uint32(entry),
+			Strong: bytes.Clone(entry[4:]),
+		})
+	}
+	err = nil
+	*sig = decoded
+	return
+}
+
+// unexpectedEOF reports a file cut short as io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package hashbuffer
+
+import (
+	"log/slog"
+	"reflect"
+	"strings"
+)
+
+// LevelTrace is the level used for per-byte progress information (every window returned, every skip).
+// It is below slog.LevelDebug, so it is only logged when a handler is explicitly configured for it.
+const LevelTrace = slog.LevelDebug - 4
+
+// TestingT is the part of *testing.T that SetTesting needs.
+type TestingT interface {
+	Helper()
+	Log(args ...any)
+}
+
+// NewTestingLogger returns a logger that writes every message, including LevelTrace, to t.Log.
+// The output is available if the test is run in verbose mode (`go test -v`).  A nil t, including a nil
+// *testing.T, returns nil, which disables logging.
+func NewTestingLogger(t TestingT) *slog.Logger {
+	if t == nil {
+		return nil
+	}
+	// a nil *testing.T is not a nil TestingT, but would panic on the first message
+	if value := reflect.ValueOf(t); value.Kind() == reflect.Pointer && value.IsNil() {
+		return nil
+	}
+	return slog.New(slog.NewTextHandler(testingWriter{t}, &slog.HandlerOptions{
+		Level: LevelTrace,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			// t.Log already records when and where
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+// testingWriter adapts TestingT to the io.Writer expected by slog handlers.
+type testingWriter struct {
+	t TestingT
+}
+
+func (w testingWriter) Write(p []byte) (n int, err error) {
+	w.t.Helper()
+	w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
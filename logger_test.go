@@ -0,0 +1,49 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// Make sure per-byte trace output is only produced when the logger is configured for LevelTrace.
+func TestLoggerLevels(t *testing.T) {
+	var out bytes.Buffer
+	testLoggerRead(t, slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	if !strings.Contains(out.String(), "Filling buffer") {
+		t.Errorf("Error TestLoggerLevels: debug output missing buffer fill: %s", out.String())
+	}
+	if strings.Contains(out.String(), "GetWindow(): start") {
+		t.Errorf("Error TestLoggerLevels: trace output logged at debug level")
+	}
+
+	out.Reset()
+	testLoggerRead(t, slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: LevelTrace})))
+	if !strings.Contains(out.String(), "GetWindow(): start") {
+		t.Errorf("Error TestLoggerLevels: trace output missing at trace level")
+	}
+}
+
+// Make sure a nil logger, or a nil *testing.T, disables logging.
+func TestLoggerNil(t *testing.T) {
+	testLoggerRead(t, nil)
+	testLoggerRead(t, NewTestingLogger(nil))
+	testLoggerRead(t, NewTestingLogger((*testing.T)(nil)))
+
+	hb, err := NewFileHashBuffer("./testdata/data_17", 1024, 16)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	hb.SetTesting((*testing.T)(nil))
+	testGet(t, hb, "TestLoggerNil", testData, 0)
+}
+
+func testLoggerRead(t *testing.T, logger *slog.Logger) {
+	hb, err := NewFileHashBuffer("./testdata/data_17", 1024, 16)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	hb.SetLogger(logger)
+	testGet(t, hb, "testLoggerRead", testData, 0)
+	testGet(t, hb, "testLoggerRead", testData, 1)
+	testGetZero(t, hb, "testLoggerRead")
+}
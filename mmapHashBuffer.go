@@ -0,0 +1,65 @@
+package hashbuffer
+
+import (
+	"os"
+)
+
+// mmapHashBuffer is a memory-mapped file based HashBuffer; windows are returned directly from the mapping.
+type mmapHashBuffer struct {
+	*abstractHashBuffer
+	// the mapped file contents; nil once unmapped
+	data []byte
+}
+
+// NewMmapHashBuffer creates a HashBuffer against the specified filespec by memory-mapping the whole file.
+// If the file cannot be mapped (an empty file, a pipe, or a platform without mmap support),
+// it falls back to a FileHashBuffer with the specified buffersize.
+func NewMmapHashBuffer(filespec string, bufferSize int, windowSize int) (hashBuffer HashBuffer, err error) {
+	return OpenMmap(filespec, positionalOptions(bufferSize, windowSize)...)
+}
+
+// OpenMmap creates a HashBuffer against the specified filespec by memory-mapping the whole file, configured
+// by options; WithWindowSize is required.  The buffer size and read-ahead only apply if the file cannot be
+// mapped, and it falls back to a FileHashBuffer.
+func OpenMmap(filespec string, options ...Option) (hashBuffer HashBuffer, err error) {
+	o, err := newOptions(options, false)
+	if err != nil {
+		return
+	}
+	f, err := os.Open(filespec)
+	if err != nil {
+		return
+	}
+	data, mapErr := mmapFile(f)
+	if mapErr != nil {
+		hashBuffer = newFileHashBuffer(f, o)
+		return
+	}
+	// the mapping stays valid after the file is closed
+	err = f.Close()
+	if err != nil {
+		munmap(data)
+		return
+	}
+
+	mhb := new(mmapHashBuffer)
+	hashBuffer = mhb
+	mhb.abstractHashBuffer = new(abstractHashBuffer)
+	mhb.data = data
+	mhb.abstractHashBuffer.initWithBuffer(data, o.windowSize)
+	mhb.abstractHashBuffer.apply(o)
+	return
+}
+
+// Close unmaps the file if it is not already unmapped; afterwards the HashBuffer returns ErrClosed.
+// Windows previously returned by GetWindow() must not be used after Close().
+func (mhb *mmapHashBuffer) Close() (err error) {
+	if mhb.data != nil {
+		// drop every reference into the mapping before releasing it
+		mhb.abstractHashBuffer.initWithBuffer(nil, mhb.windowSize)
+		err = munmap(mhb.data)
+		mhb.data = nil
+	}
+	mhb.closed = true
+	return
+}
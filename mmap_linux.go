@@ -0,0 +1,31 @@
+package hashbuffer
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the entire contents of f read-only.
+func mmapFile(f *os.File) (data []byte, err error) {
+	info, err := f.Stat()
+	if err != nil {
+		return
+	}
+	size := info.Size()
+	if !info.Mode().IsRegular() || size <= 0 || int64(int(size)) != size {
+		err = errors.New("hashbuffer: file cannot be memory-mapped")
+		return
+	}
+	data, err = syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err == nil {
+		// windows are consumed front to back
+		syscall.Madvise(data, syscall.MADV_SEQUENTIAL)
+	}
+	return
+}
+
+// munmap releases a mapping created by mmapFile.
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
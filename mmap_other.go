@@ -0,0 +1,19 @@
+//go:build !linux
+
+package hashbuffer
+
+import (
+	"errors"
+	"os"
+)
+
+// mmapFile is not supported on this platform; NewMmapHashBuffer falls back to a FileHashBuffer.
+func mmapFile(f *os.File) (data []byte, err error) {
+	err = errors.New("hashbuffer: memory-mapping is not supported on this platform")
+	return
+}
+
+// munmap is never called on this platform.
+func munmap(data []byte) error {
+	return nil
+}
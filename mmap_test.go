@@ -0,0 +1,81 @@
+package hashbuffer
+
+import (
+	"runtime"
+	"testing"
+)
+
+// Make sure a memory-mapped file returns the same windows as a buffered file.
+func TestMmapCompareToFile(t *testing.T) {
+	testMmapCompareToFile(t, "./testdata/data_0", "TestMmap_0")
+	testMmapCompareToFile(t, "./testdata/data_1", "TestMmap_1")
+	testMmapCompareToFile(t, "./testdata/data_15", "TestMmap_15")
+	testMmapCompareToFile(t, "./testdata/data_16", "TestMmap_16")
+	testMmapCompareToFile(t, "./testdata/data_17", "TestMmap_17")
+	testMmapCompareToFile(t, "./testdata/data_1025", "TestMmap_1025")
+	testMmapCompareToFile(t, "./testdata/data_long", "TestMmap_long")
+}
+
+// Make sure the file is actually mapped where supported, and an empty file falls back to buffered reads.
+func TestMmapFallback(t *testing.T) {
+	const bufferSize = 1024
+	const windowSize = 16
+
+	hb, err := NewMmapHashBuffer("./testdata/data_long", bufferSize, windowSize)
+	check(t, err)
+	if _, mapped := hb.(*mmapHashBuffer); mapped != (runtime.GOOS == "linux") {
+		t.Errorf("Error TestMmapFallback: got %T for data_long on %s", hb, runtime.GOOS)
+	}
+	closeTestHashBuffer(t, hb)
+
+	hb, err = NewMmapHashBuffer("./testdata/data_0", bufferSize, windowSize)
+	check(t, err)
+	if _, ok := hb.(*fileHashBuffer); !ok {
+		t.Errorf("Error TestMmapFallback: got %T for data_0, want *fileHashBuffer", hb)
+	}
+	closeTestHashBuffer(t, hb)
+}
+
+// Make sure Close() can be called more than once, and that no data is returned after it.
+func TestMmapClose(t *testing.T) {
+	const title = "TestMmapClose"
+
+	hb, err := NewMmapHashBuffer("./testdata/data_1025", 1024, 16)
+	check(t, err)
+	testGet(t, hb, title, testData, 0)
+	check(t, hb.Close())
+	check(t, hb.Close())
+	testClosed(t, hb, title)
+}
+
+func testMmapCompareToFile(t *testing.T, filename string, title string) {
+	const bufferSize = 1024
+	const windowSize = 16
+
+	t.Logf("start %s", title)
+	hb, err := NewMmapHashBuffer(filename, bufferSize, windowSize)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	fhb, err := NewFileHashBuffer(filename, bufferSize, windowSize)
+	check(t, err)
+	defer closeTestHashBuffer(t, fhb)
+	skipped, err := hb.Skip(3)
+	check(t, err)
+	fskipped, err := fhb.Skip(3)
+	check(t, err)
+	if skipped != fskipped {
+		t.Errorf("Error %s: skipped %d, want %d", title, skipped, fskipped)
+	}
+	for i := 0; ; i++ {
+		window, err := hb.GetWindow()
+		check(t, err)
+		want, err := fhb.GetWindow()
+		check(t, err)
+		if !testEq(window, want) {
+			t.Fatalf("Error %s: window %d is %#x, want %#x", title, i, window, want)
+		}
+		if len(want) == 0 {
+			break
+		}
+	}
+}
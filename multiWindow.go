@@ -0,0 +1,111 @@
+package hashbuffer
+
+import (
+	"slices"
+)
+
+// Alignment sets how the windows of several sizes returned by GetWindows() line up (see WithWindowSizes).
+type Alignment int
+
+const (
+	// AlignStart makes every window start at Offset(), as the largest does.
+	AlignStart Alignment = iota
+	// AlignEnd makes every window end where the largest does, so a window of size n starts at
+	// Offset() plus the largest size minus n.
+	AlignEnd
+)
+
+// GetWindows returns the windows of each of the sizes set with WithWindowSizes, in the order given, all of them
+// sub-slices of the largest window, which GetWindow() returns.  Every position of any of the windows is
+// returned: with AlignEnd, the smaller windows that end before the first largest window are returned first,
+// and with AlignStart, those that start after the last one are returned last.  At those positions the entries
+// of the windows that do not fit, including the largest, are nil, and Offset() does not move to them.  Near the end
+// of a stream shorter than the largest window, the windows at its one position are cut to the data there
+// is.  It returns no windows when there is no more data.
+func (ahb *abstractHashBuffer) GetWindows() (windows [][]byte, err error) {
+	if ahb.windowSizes != nil && ahb.alignment == AlignEnd && ahb.Offset() < 0 {
+		windows, err = ahb.leadingWindows()
+		if err != nil || windows != nil {
+			return
+		}
+	}
+	if ahb.windowSizes != nil && ahb.alignment == AlignStart && ahb.edgePositions > 0 {
+		return ahb.trailingWindows()
+	}
+	previous := ahb.Offset()
+	window, err := ahb.GetWindow()
+	if err != nil {
+		return
+	}
+	if len(window) == 0 {
+		if ahb.windowSizes != nil && ahb.alignment == AlignStart {
+			ahb.edgeBase = previous
+			return ahb.trailingWindows()
+		}
+		return
+	}
+	if ahb.windowSizes == nil {
+		ahb.windows = append(ahb.windows[:0], window)
+		windows = ahb.windows
+		return
+	}
+	for i, windowSize := range ahb.windowSizes {
+		windowSize = min(windowSize, len(window))
+		if ahb.alignment == AlignEnd {
+			ahb.windows[i] = window[len(window)-windowSize:]
+		} else {
+			ahb.windows[i] = window[:windowSize]
+		}
+	}
+	windows = ahb.windows
+	return
+}
+
+// leadingWindows returns the next of the positions, a stride apart, before the first position of the
+// largest window, where only smaller windows fit, with AlignEnd; it returns nil once there are no more.
+func (ahb *abstractHashBuffer) leadingWindows() (windows [][]byte, err error) {
+	// the start of the stream, up to the end of the first largest window
+	data, err := ahb.Peek(ahb.windowSize)
+	if err != nil {
+		return
+	}
+	positions := (len(data) - slices.Min(ahb.windowSizes)) / ahb.stride
+	if len(data) == 0 || ahb.edgePositions >= positions {
+		return
+	}
+	end := len(data) - (positions-ahb.edgePositions)*ahb.stride
+	ahb.edgePositions++
+	for i, windowSize := range ahb.windowSizes {
+		ahb.windows[i] = nil
+		if windowSize <= end && windowSize < ahb.windowSize {
+			ahb.windows[i] = data[end-windowSize : end]
+		}
+	}
+	windows = ahb.windows
+	return
+}
+
+// trailingWindows returns the next of the positions, a stride apart, after the last position of the
+// largest window, where only smaller windows fit, with AlignStart; it returns nil once there are no more.
+func (ahb *abstractHashBuffer) trailingWindows() (windows [][]byte, err error) {
+	// the rest of the stream: stepping past the last largest window may have left the current window one
+	// byte before it, as Skip() does, but no further
+	data, err := ahb.Peek(ahb.windowSize + 1)
+	if err != nil {
+		return
+	}
+	ahb.edgePositions++
+	start := int(ahb.edgeBase + int64(ahb.edgePositions*ahb.stride) - ahb.Offset())
+	found := false
+	for i, windowSize := range ahb.windowSizes {
+		ahb.windows[i] = nil
+		if start >= 0 && start+windowSize <= len(data) && windowSize < ahb.windowSize {
+			ahb.windows[i] = data[start : start+windowSize]
+			found = true
+		}
+	}
+	if found {
+		windows = ahb.windows
+	}
+	return
+}
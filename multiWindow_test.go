@@ -0,0 +1,134 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// Make sure GetWindows() returns windows of each size at every position, aligned at the start or the end, with
+// nil for the windows that do not fit at the edges of the stream.
+func TestGetWindows(t *testing.T) {
+	sizes := []int{8, 4, 32}
+	for _, size := range []int{0, 3, 6, 20, 32, 1023, 1024, 1025} {
+		for _, alignment := range []Alignment{AlignStart, AlignEnd} {
+			for _, stride := range []int{1, 3} {
+				title := fmt.Sprintf("TestGetWindows_%d_%d_%d", size, alignment, stride)
+				hb, err := NewReader(bytes.NewReader(testData[:size]), WithBufferSize(64), WithWindowSizes(sizes...),
+					WithAlignment(alignment), WithStride(stride))
+				check(t, err)
+				want := testWantWindows(sizes, size, alignment, stride)
+				for position, wantWindows := range want {
+					windows, err := hb.GetWindows()
+					check(t, err)
+					if len(windows) != len(sizes) {
+						t.Fatalf("Error %s: got %d windows at position %d, want %d", title, len(windows), position, len(sizes))
+					}
+					for i := range sizes {
+						if (windows[i] == nil) != (wantWindows[i] == nil) || !bytes.Equal(windows[i], wantWindows[i]) {
+							t.Fatalf("Error %s: window of %d bytes at position %d is %#x, want %#x", title, sizes[i], position,
+								windows[i], wantWindows[i])
+						}
+					}
+				}
+				if windows, err := hb.GetWindows(); err != nil || windows != nil {
+					t.Errorf("Error %s: got %d windows, %v after the end, want none", title, len(windows), err)
+				}
+				closeTestHashBuffer(t, hb)
+			}
+		}
+	}
+}
+
+// testWantWindows returns the windows GetWindows() should return for the first size bytes of testData, at
+// each position in turn.
+func testWantWindows(sizes []int, size int, alignment Alignment, stride int) (want [][][]byte) {
+	if size == 0 {
+		return
+	}
+	largest := min(32, size)
+	// the positions of the largest window, from the start of the stream for AlignStart and the end for AlignEnd
+	var positions []int
+	for start := 0; start+largest <= size; start += stride {
+		positions = append(positions, start)
+	}
+	window := func(start int, windowSize int) []byte {
+		if start < 0 || start+windowSize > size {
+			return nil
+		}
+		return testData[start : start+windowSize]
+	}
+	if alignment == AlignEnd {
+		for end := largest - stride; end >= 4; end -= stride {
+			windows := make([][]byte, len(sizes))
+			for i, windowSize := range sizes {
+				if windowSize < 32 {
+					windows[i] = window(end-windowSize, windowSize)
+				}
+			}
+			want = append([][][]byte{windows}, want...)
+		}
+		for _, start := range positions {
+			windows := make([][]byte, len(sizes))
+			for i, windowSize := range sizes {
+				windowSize = min(windowSize, largest)
+				windows[i] = window(start+largest-windowSize, windowSize)
+			}
+			want = append(want, windows)
+		}
+		return
+	}
+	for _, start := range positions {
+		windows := make([][]byte, len(sizes))
+		for i, windowSize := range sizes {
+			windows[i] = window(start, min(windowSize, largest))
+		}
+		want = append(want, windows)
+	}
+	for start := positions[len(positions)-1] + stride; start+4 <= size; start += stride {
+		windows := make([][]byte, len(sizes))
+		for i, windowSize := range sizes {
+			if windowSize < 32 {
+				windows[i] = window(start, windowSize)
+			}
+		}
+		want = append(want, windows)
+	}
+	return
+}
+
+// Make sure GetWindows() returns the window of GetWindow() without WithWindowSizes, and a stride applies to it.
+func TestGetWindowsStride(t *testing.T) {
+	const title = "TestGetWindowsStride"
+
+	hb, err := NewBytes(testData, WithWindowSize(16), WithStride(16))
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	for offset := 0; offset < 64; offset += 16 {
+		windows, err := hb.GetWindows()
+		check(t, err)
+		if len(windows) != 1 || !testEq(windows[0], testData[offset:offset+16]) {
+			t.Errorf("Error %s: windows at offset %d do not match", title, offset)
+		}
+	}
+}
+
+// Make sure invalid window sizes and alignments are rejected.
+func TestGetWindowsInvalid(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		options []Option
+		want    error
+	}{
+		{"no sizes", []Option{WithWindowSizes()}, ErrInvalidWindowSize},
+		{"zero size", []Option{WithWindowSizes(4, 0)}, ErrInvalidWindowSize},
+		{"smaller window size", []Option{WithWindowSizes(4, 32), WithWindowSize(16)}, ErrInvalidWindowSize},
+		{"unknown alignment", []Option{WithWindowSizes(4, 32), WithAlignment(2)}, ErrInvalidOption},
+	} {
+		hb, err := NewBytes(testData, test.options...)
+		if !errors.Is(err, test.want) || hb != nil {
+			t.Errorf("Error TestGetWindowsInvalid: %s got %v, want %v", test.name, err, test.want)
+		}
+	}
+}
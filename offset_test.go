@@ -0,0 +1,88 @@
+package hashbuffer
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Make sure Offset() tracks the window start across buffer refills.
+func TestOffsetWithGet(t *testing.T) {
+	testOffsetWithGet(t, "./testdata/data_0", "TestOffset_0", 0)
+	testOffsetWithGet(t, "./testdata/data_15", "TestOffset_15", 15)
+	testOffsetWithGet(t, "./testdata/data_1024", "TestOffset_1024", 1024)
+	testOffsetWithGet(t, "./testdata/data_1025", "TestOffset_1025", 1025)
+	testOffsetWithGet(t, "./testdata/data_long", "TestOffset_long", 35539)
+}
+
+// Make sure Skip() moves Offset() forward exactly as GetNext() would.
+func TestOffsetWithSkip(t *testing.T) {
+	const title = "TestOffsetWithSkip"
+
+	hb, err := NewFileHashBuffer("./testdata/data_long", 1024, 16)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	testOffset(t, hb, title, -1)
+	skipped, err := hb.Skip(5)
+	check(t, err)
+	testOffset(t, hb, title, int64(skipped)-1)
+	testGet(t, hb, title, testData, 5)
+	testOffset(t, hb, title, 5)
+	for _, count := range []int{100, 1000, 3000, 20000} {
+		want := hb.Offset()
+		skipped, err = hb.Skip(count)
+		check(t, err)
+		testOffset(t, hb, title, want+int64(skipped))
+		window := testGet(t, hb, title, testData, int(hb.Offset())+1)
+		testOffset(t, hb, title, want+int64(skipped)+1)
+		if len(window) == 0 {
+			t.Fatalf("Error %s: unexpected end of data", title)
+		}
+	}
+	if hb.TotalRead() > int64(len(testData)) {
+		t.Errorf("Error %s: TotalRead() %d is larger than the file", title, hb.TotalRead())
+	}
+}
+
+// Make sure the in-memory implementation reports offsets the same way.
+func TestOffsetBytes(t *testing.T) {
+	const title = "TestOffsetBytes"
+
+	hb, err := NewBytesHashBuffer(testData, 16)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	testOffset(t, hb, title, -1)
+	if hb.TotalRead() != int64(len(testData)) {
+		t.Errorf("Error %s: TotalRead() %d, want %d", title, hb.TotalRead(), len(testData))
+	}
+	_, err = hb.Skip(1000)
+	check(t, err)
+	testGet(t, hb, title, testData, 1000)
+	testOffset(t, hb, title, 1000)
+}
+
+func testOffsetWithGet(t *testing.T, filename string, title string, expectedSize int) {
+	const bufferSize = 1024
+	const windowSize = 16
+
+	t.Logf("start %s", title)
+	hb, err := NewFileHashBuffer(filename, bufferSize, windowSize)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	for i := 0; ; i++ {
+		window := testGet(t, hb, fmt.Sprintf("%s round %d", title, i), testData, i)
+		if len(window) == 0 {
+			break
+		}
+		testOffset(t, hb, title, int64(i))
+	}
+	if hb.TotalRead() != int64(expectedSize) {
+		t.Errorf("Error %s: TotalRead() %d, want %d", title, hb.TotalRead(), expectedSize)
+	}
+}
+
+func testOffset(t *testing.T, hb HashBuffer, title string, want int64) {
+	t.Helper()
+	if got := hb.Offset(); got != want {
+		t.Errorf("Error %s: Offset() %d, want %d", title, got, want)
+	}
+}
@@ -0,0 +1,205 @@
+package hashbuffer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// DefaultBufferSize is the buffer size used when WithBufferSize is not given (or twice the window size, if that is larger).
+const DefaultBufferSize = 64 * 1024
+
+// Option configures a HashBuffer created with OpenFile, NewReader, NewBytes or OpenMmap.
+type Option func(*options) error
+
+// options holds the settings made by Options; zero sizes are unset.
+type options struct {
+	bufferSize int
+	windowSize int
+	logger     *slog.Logger
+	stride     int
+	readAhead  bool
+	strategy   BufferStrategy
+	ctx        context.Context
+	// set by WithWindowSizes, with windowSize the largest of them
+	windowSizes []int
+	alignment   Alignment
+}
+
+// WithBufferSize sets the size of the buffer the stream is read into; it must be larger than the window size,
+// or Skip() and strides could not move past a full buffer.
+// It does not apply to NewBytes, where the data itself is the buffer.
+func WithBufferSize(bufferSize int) Option {
+	return func(o *options) error {
+		if bufferSize <= 0 {
+			return fmt.Errorf("%w: got %d", ErrInvalidBufferSize, bufferSize)
+		}
+		o.bufferSize = bufferSize
+		return nil
+	}
+}
+
+// WithWindowSize sets the size of the windows returned; it is required.
+func WithWindowSize(windowSize int) Option {
+	return func(o *options) error {
+		if windowSize <= 0 {
+			return fmt.Errorf("%w: got %d", ErrInvalidWindowSize, windowSize)
+		}
+		o.windowSize = windowSize
+		return nil
+	}
+}
+
+// WithWindowSizes sets several window sizes, whose windows GetWindows() returns together; it replaces
+// WithWindowSize, setting the window size of GetWindow() and Offset() to the largest of them.
+func WithWindowSizes(windowSizes ...int) Option {
+	return func(o *options) error {
+		if len(windowSizes) == 0 {
+			return fmt.Errorf("%w: no window sizes", ErrInvalidWindowSize)
+		}
+		o.windowSize = 0
+		for _, windowSize := range windowSizes {
+			if windowSize <= 0 {
+				return fmt.Errorf("%w: got %d", ErrInvalidWindowSize, windowSize)
+			}
+			o.windowSize = max(o.windowSize, windowSize)
+		}
+		o.windowSizes = windowSizes
+		return nil
+	}
+}
+
+// WithAlignment sets whether the windows of WithWindowSizes start (AlignStart, the default) or end (AlignEnd)
+// with the largest window.
+func WithAlignment(alignment Alignment) Option {
+	return func(o *options) error {
+		if alignment != AlignStart && alignment != AlignEnd {
+			return fmt.Errorf("%w: unknown alignment %d", ErrInvalidOption, alignment)
+		}
+		o.alignment = alignment
+		return nil
+	}
+}
+
+// WithLogger sets the logger, as SetLogger does.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) error {
+		o.logger = logger
+		return nil
+	}
+}
+
+// WithStride makes each window returned by GetWindow() start stride bytes after the previous one, rather
+// than one byte; a stride equal to the window size gives non-overlapping blocks.  GetWindow() returns
+// no more windows once there is no full window a stride away.  GetNext() and GetRoll() still advance one byte,
+// and GetWindowStride() and WindowsStride() use the stride they are given.
+func WithStride(stride int) Option {
+	return func(o *options) error {
+		if stride <= 0 {
+			return fmt.Errorf("%w: got %d", ErrInvalidStride, stride)
+		}
+		o.stride = stride
+		return nil
+	}
+}
+
+// WithReadAhead reads the stream on a goroutine, so the next buffer's worth of data is being read while the
+// windows of the current one are hashed.  It uses two more buffers of the buffer size.  Skip() reads through
+// the data rather than seeking, and Close() waits for a read in progress.  It does not apply to NewBytes,
+// where all the data is already in memory.
+func WithReadAhead(readAhead bool) Option {
+	return func(o *options) error {
+		o.readAhead = readAhead
+		return nil
+	}
+}
+
+// WithBufferStrategy selects how room is made in the buffer for more data; see BufferStrategy.  It does not
+// apply to NewBytes, where the data itself is the buffer.
+func WithBufferStrategy(strategy BufferStrategy) Option {
+	return func(o *options) error {
+		if strategy != CompactBuffer && strategy != RingBuffer {
+			return fmt.Errorf("%w: unknown buffer strategy %d", ErrInvalidOption, strategy)
+		}
+		o.strategy = strategy
+		return nil
+	}
+}
+
+// WithContext makes GetWindow(), GetNext(), GetRoll() and Skip() return ctx.Err() once ctx is cancelled or
+// its deadline passes.  A call already waiting for the read-ahead goroutine (WithReadAhead) returns at once;
+// otherwise a read of the stream in progress finishes first, since an io.Reader cannot be interrupted.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) error {
+		if ctx == nil {
+			return fmt.Errorf("%w: nil context", ErrInvalidOption)
+		}
+		o.ctx = ctx
+		return nil
+	}
+}
+
+// newOptions applies opts, then checks the result and fills in the defaults.
+// inMemory is set for HashBuffers without a read buffer, which reject the options that only apply to one.
+func newOptions(opts []Option, inMemory bool) (o *options, err error) {
+	o = &options{stride: 1}
+	for _, opt := range opts {
+		err = opt(o)
+		if err != nil {
+			return
+		}
+	}
+	if o.windowSize == 0 {
+		err = fmt.Errorf("%w: WithWindowSize is required", ErrInvalidWindowSize)
+		return
+	}
+	if o.windowSizes != nil && o.windowSize != slices.Max(o.windowSizes) {
+		err = fmt.Errorf("%w: window size %d is not the largest of the window sizes %v", ErrInvalidWindowSize, o.windowSize, o.windowSizes)
+		return
+	}
+	if inMemory && o.bufferSize != 0 {
+		err = fmt.Errorf("%w: buffer size of in-memory data", ErrInvalidOption)
+		return
+	}
+	if inMemory && o.readAhead {
+		err = fmt.Errorf("%w: read-ahead of in-memory data", ErrInvalidOption)
+		return
+	}
+	if inMemory && o.strategy != CompactBuffer {
+		err = fmt.Errorf("%w: buffer strategy of in-memory data", ErrInvalidOption)
+		return
+	}
+	if o.bufferSize == 0 && !inMemory {
+		o.bufferSize = max(DefaultBufferSize, 2*o.windowSize)
+	}
+	if !inMemory && o.bufferSize <= o.windowSize {
+		err = fmt.Errorf("%w: buffer size %d is not larger than window size %d", ErrInvalidBufferSize, o.bufferSize, o.windowSize)
+	}
+	return
+}
+
+// positionalOptions returns the options equivalent to the sizes given to the original constructors, which
+// enlarge a buffer no larger than the window, to twice the window size, rather than rejecting it.
+func positionalOptions(bufferSize int, windowSize int) []Option {
+	if bufferSize > 0 && bufferSize <= windowSize {
+		bufferSize = 2 * windowSize
+	}
+	return []Option{WithBufferSize(bufferSize), WithWindowSize(windowSize)}
+}
+
+// apply sets the options that are not needed to initialize the buffer.
+func (ahb *abstractHashBuffer) apply(o *options) {
+	ahb.logger = o.logger
+	ahb.stride = o.stride
+	ahb.strategy = o.strategy
+	if o.windowSizes != nil {
+		ahb.windowSizes = slices.Clone(o.windowSizes)
+		ahb.windows = make([][]byte, len(o.windowSizes))
+	}
+	ahb.alignment = o.alignment
+	if o.ctx != nil {
+		ahb.ctx = o.ctx
+		ahb.done = o.ctx.Done()
+	}
+}
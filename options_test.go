@@ -0,0 +1,159 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// Make sure each constructor that takes options returns the same windows as the original constructors.
+func TestOptionsConstructors(t *testing.T) {
+	constructors := map[string]func() (HashBuffer, error){
+		"OpenFile": func() (HashBuffer, error) {
+			return OpenFile("./testdata/data_long", WithWindowSize(16), WithBufferSize(1024))
+		},
+		"OpenFile_default": func() (HashBuffer, error) {
+			return OpenFile("./testdata/data_long", WithWindowSize(16))
+		},
+		"NewReader": func() (HashBuffer, error) {
+			return NewReader(bytes.NewReader(testData), WithWindowSize(16), WithBufferSize(100))
+		},
+		"NewBytes": func() (HashBuffer, error) {
+			return NewBytes(testData, WithWindowSize(16))
+		},
+		"OpenMmap": func() (HashBuffer, error) {
+			return OpenMmap("./testdata/data_long", WithWindowSize(16))
+		},
+	}
+	for name, constructor := range constructors {
+		title := "TestOptionsConstructors_" + name
+		hb, err := constructor()
+		check(t, err)
+		for i := 0; i+16 <= len(testData); i++ {
+			testGet(t, hb, title, testData, i)
+		}
+		testGetZero(t, hb, title)
+		closeTestHashBuffer(t, hb)
+	}
+}
+
+// Make sure the original constructors still enlarge a buffer no larger than the window, and its size is used.
+func TestOptionsBufferSize(t *testing.T) {
+	const title = "TestOptionsBufferSize"
+
+	for _, bufferSize := range []int{8, 16} {
+		hb, err := NewFileHashBuffer("./testdata/data_long", bufferSize, 16)
+		check(t, err)
+		if size := len(hb.(*fileHashBuffer).buffer); size != 32 {
+			t.Errorf("Error %s: buffer of %d bytes for %d, want 32", title, size, bufferSize)
+		}
+		testGet(t, hb, title, testData, 0)
+		// a full buffer still leaves room to move on
+		skipped, err := hb.Skip(1 << 40)
+		check(t, err)
+		if want := len(testData) - 17; skipped != want {
+			t.Errorf("Error %s: skipped %d, should have skipped %d", title, skipped, want)
+		}
+		closeTestHashBuffer(t, hb)
+	}
+
+	hb2, err := NewReader(bytes.NewReader(testData), WithWindowSize(16))
+	check(t, err)
+	defer closeTestHashBuffer(t, hb2)
+	if size := len(hb2.(*readerHashBuffer).buffer); size != DefaultBufferSize {
+		t.Errorf("Error %s: default buffer of %d bytes, want %d", title, size, DefaultBufferSize)
+	}
+}
+
+// Make sure invalid options, and combinations of them, are rejected with the matching error.
+func TestOptionsInvalid(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		inMemory bool
+		options  []Option
+		want     error
+	}{
+		{"no window size", false, nil, ErrInvalidWindowSize},
+		{"zero window size", false, []Option{WithWindowSize(0)}, ErrInvalidWindowSize},
+		{"negative buffer size", false, []Option{WithWindowSize(16), WithBufferSize(-1)}, ErrInvalidBufferSize},
+		{"buffer smaller than window", false, []Option{WithWindowSize(16), WithBufferSize(8)}, ErrInvalidBufferSize},
+		{"buffer the size of the window", false, []Option{WithWindowSize(16), WithBufferSize(16)}, ErrInvalidBufferSize},
+		{"zero stride", false, []Option{WithWindowSize(16), WithStride(0)}, ErrInvalidStride},
+		{"buffer size of bytes", true, []Option{WithWindowSize(16), WithBufferSize(1024)}, ErrInvalidOption},
+		{"read-ahead of bytes", true, []Option{WithWindowSize(16), WithReadAhead(true)}, ErrInvalidOption},
+		{"buffer strategy of bytes", true, []Option{WithWindowSize(16), WithBufferStrategy(RingBuffer)}, ErrInvalidOption},
+		{"unknown buffer strategy", false, []Option{WithWindowSize(16), WithBufferStrategy(-1)}, ErrInvalidOption},
+	} {
+		var hb HashBuffer
+		var err error
+		if test.inMemory {
+			hb, err = NewBytes(testData, test.options...)
+		} else {
+			hb, err = OpenFile("./testdata/data_long", test.options...)
+		}
+		if !errors.Is(err, test.want) || hb != nil {
+			t.Errorf("Error TestOptionsInvalid: %s got %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+// Make sure GetWindow() and Windows() move forward by the stride, and stop when there is no full window a stride away.
+func TestOptionsStride(t *testing.T) {
+	for _, stride := range []int{1, 4, 16, 17, 1000} {
+		title := fmt.Sprintf("TestOptionsStride_%d", stride)
+		hb, err := OpenFile("./testdata/data_1025", WithWindowSize(16), WithBufferSize(1024), WithStride(stride))
+		check(t, err)
+		i := 0
+		for ; i+16 <= 1025; i += stride {
+			testGet(t, hb, title, testData, i)
+			testOffset(t, hb, title, int64(i))
+		}
+		testGetZero(t, hb, title)
+		closeTestHashBuffer(t, hb)
+
+		hb, err = NewBytes(testData[:1025], WithWindowSize(16), WithStride(stride))
+		check(t, err)
+		count := 0
+		for window, err := range hb.Windows() {
+			check(t, err)
+			if !testEq(window, testData[count*stride:count*stride+16]) {
+				t.Errorf("Error %s: window %d does not match", title, count)
+			}
+			count++
+		}
+		if want := (1025-16)/stride + 1; count != want {
+			t.Errorf("Error %s: got %d windows, want %d", title, count, want)
+		}
+		closeTestHashBuffer(t, hb)
+	}
+}
+
+// Make sure GetNext() still advances one byte with a stride.
+func TestOptionsStrideGetNext(t *testing.T) {
+	const title = "TestOptionsStrideGetNext"
+
+	hb, err := NewBytes(testData, WithWindowSize(16), WithStride(16))
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	testGet(t, hb, title, testData, 0)
+	testGetNextOne(t, hb, title, testData[16])
+	testGet(t, hb, title, testData, 17)
+}
+
+// Make sure the logger given with WithLogger is used.
+func TestOptionsLogger(t *testing.T) {
+	const title = "TestOptionsLogger"
+
+	var output strings.Builder
+	logger := slog.New(slog.NewTextHandler(&output, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	hb, err := NewReader(bytes.NewReader(testData), WithWindowSize(16), WithLogger(logger))
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	testGet(t, hb, title, testData, 0)
+	if !strings.Contains(output.String(), "Filling buffer") {
+		t.Errorf("Error %s: nothing logged", title)
+	}
+}
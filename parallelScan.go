@@ -0,0 +1,103 @@
+package hashbuffer
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// ParallelScan hashes the size bytes of r on several goroutines.  It splits them into regions that overlap by
+// windowSize-1 bytes, so that between them they have every window a single HashBuffer would return, and each
+// window is in exactly one region.  scan is called for each region, on its own goroutine, with a HashBuffer
+// (created by NewReader with options) over the region and the stream offset of the region's first window,
+// which is to be added to Offset().  The results of each region are appended in stream order.
+//
+// regions is the number of regions, or (if 0 or less) runtime.GOMAXPROCS(0); there are fewer when there are
+// fewer windows.  WithWindowSize is required.  With WithStride, regions start at a multiple of the stride.
+// The first error returned by scan, or by a constructor, is returned, and the other regions are cancelled
+// through their context (see WithContext).  scan does not need to close the HashBuffer.
+func ParallelScan[T any](r io.ReaderAt, size int64, regions int, scan func(hb HashBuffer, base int64) ([]T, error),
+	options ...Option) (results []T, err error) {
+	o, err := newOptions(options, false)
+	if err != nil {
+		return
+	}
+	parent := o.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	// the regions are later options, so they replace any context given
+	options = append(options[:len(options):len(options)], WithContext(ctx))
+
+	bounds := parallelRegions(size, o.windowSize, o.stride, regions)
+	regionResults := make([][]T, len(bounds))
+	var firstErr error
+	var once sync.Once
+	var wg sync.WaitGroup
+	for i, bound := range bounds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var regionErr error
+			regionResults[i], regionErr = scanRegion(r, bound, scan, options)
+			if regionErr != nil {
+				// the regions cancelled because of it fail later
+				once.Do(func() {
+					firstErr = regionErr
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		err = firstErr
+		return
+	}
+	for _, regionResult := range regionResults {
+		results = append(results, regionResult...)
+	}
+	return
+}
+
+// parallelRegion is the part of the stream given to one goroutine: the windows starting from start, up to
+// the start of the next region, and the windowSize-1 bytes after them.
+type parallelRegion struct {
+	start  int64
+	length int64
+}
+
+// parallelRegions divides the windows of a stream of size bytes, one every stride bytes, between at most count regions.
+func parallelRegions(size int64, windowSize int, stride int, count int) (bounds []parallelRegion) {
+	if count <= 0 {
+		count = runtime.GOMAXPROCS(0)
+	}
+	// a stream shorter than the window has one short window
+	if size <= int64(windowSize) {
+		return []parallelRegion{{0, size}}
+	}
+	windows := (size-int64(windowSize))/int64(stride) + 1
+	count = int(min(int64(count), windows))
+	for i := range count {
+		// the regions share out the windows as evenly as they can
+		first := windows * int64(i) / int64(count)
+		next := windows * int64(i+1) / int64(count)
+		start := first * int64(stride)
+		bounds = append(bounds, parallelRegion{start, (next-first-1)*int64(stride) + int64(windowSize)})
+	}
+	return
+}
+
+// scanRegion runs scan over one region.
+func scanRegion[T any](r io.ReaderAt, bound parallelRegion, scan func(hb HashBuffer, base int64) ([]T, error),
+	options []Option) (results []T, err error) {
+	hb, err := NewReader(io.NewSectionReader(r, bound.start, bound.length), options...)
+	if err != nil {
+		return
+	}
+	defer hb.Close()
+	return scan(hb, bound.start)
+}
@@ -0,0 +1,135 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// windowHash is the result of testScanWindows for one window.
+type windowHash struct {
+	offset int64
+	sum    uint32
+}
+
+// testScanWindows is a ParallelScan function that hashes every window with its stream offset.
+func testScanWindows(hb HashBuffer, base int64) (hashes []windowHash, err error) {
+	for window, err := range hb.Windows() {
+		if err != nil {
+			return hashes, err
+		}
+		hashes = append(hashes, windowHash{base + hb.Offset(), crc32.ChecksumIEEE(window)})
+	}
+	return
+}
+
+// errorReaderAt fails reads that reach offset.
+type errorReaderAt struct {
+	*bytes.Reader
+	offset int64
+}
+
+func (r errorReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off+int64(len(p)) > r.offset {
+		return 0, errTestRead
+	}
+	return r.Reader.ReadAt(p, off)
+}
+
+// Make sure ParallelScan of data_long returns exactly what a serial scan of it does, however it is split.
+func TestParallelScan(t *testing.T) {
+	file, err := os.Open("./testdata/data_long")
+	check(t, err)
+	defer file.Close()
+	info, err := file.Stat()
+	check(t, err)
+	for _, stride := range []int{1, 7, 16} {
+		hb, err := OpenFile("./testdata/data_long", WithWindowSize(16), WithStride(stride))
+		check(t, err)
+		serial, err := testScanWindows(hb, 0)
+		check(t, err)
+		closeTestHashBuffer(t, hb)
+		if want := (len(testData)-16)/stride + 1; len(serial) != want {
+			t.Fatalf("Error TestParallelScan: serial scan with stride %d got %d windows, want %d", stride, len(serial), want)
+		}
+		for _, regions := range []int{0, 1, 2, 3, 8, 1000, 100000} {
+			title := fmt.Sprintf("TestParallelScan_%d_%d", stride, regions)
+			parallel, err := ParallelScan(file, info.Size(), regions, testScanWindows,
+				WithWindowSize(16), WithBufferSize(1024), WithStride(stride))
+			check(t, err)
+			if !slices.Equal(parallel, serial) {
+				t.Errorf("Error %s: got %d windows, which differ from the %d of the serial scan", title, len(parallel), len(serial))
+			}
+		}
+	}
+}
+
+// Make sure streams with few or no full windows are scanned as a single HashBuffer would.
+func TestParallelScanShort(t *testing.T) {
+	for _, size := range []int{0, 1, 15, 16, 17, 20} {
+		title := fmt.Sprintf("TestParallelScanShort_%d", size)
+		hb, err := NewBytes(testData[:size], WithWindowSize(16))
+		check(t, err)
+		serial, err := testScanWindows(hb, 0)
+		check(t, err)
+		parallel, err := ParallelScan(bytes.NewReader(testData[:size]), int64(size), 4, testScanWindows, WithWindowSize(16))
+		check(t, err)
+		if !slices.Equal(parallel, serial) {
+			t.Errorf("Error %s: got %v, want %v", title, parallel, serial)
+		}
+	}
+}
+
+// Make sure a read error in a region is returned as a *ReadError.
+func TestParallelScanError(t *testing.T) {
+	const title = "TestParallelScanError"
+
+	reader := errorReaderAt{bytes.NewReader(testData), 20000}
+	_, err := ParallelScan(reader, int64(len(testData)), 8, testScanWindows, WithWindowSize(16), WithBufferSize(64))
+	var readError *ReadError
+	if !errors.As(err, &readError) || !errors.Is(err, errTestRead) {
+		t.Errorf("Error %s: got %v, want the reader's error", title, err)
+	}
+
+	_, err = ParallelScan(reader, int64(len(testData)), 8, testScanWindows)
+	if !errors.Is(err, ErrInvalidWindowSize) {
+		t.Errorf("Error %s: got %v without a window size, want ErrInvalidWindowSize", title, err)
+	}
+}
+
+// Make sure the scan function's own error is returned, and the other regions are cancelled.
+func TestParallelScanCancel(t *testing.T) {
+	const title = "TestParallelScanCancel"
+
+	var cancelled atomic.Int32
+	scan := func(hb HashBuffer, base int64) ([]windowHash, error) {
+		if base == 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		// the other regions wait for the cancellation
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+			if _, err := hb.Skip(0); err != nil {
+				if errors.Is(err, context.Canceled) {
+					cancelled.Add(1)
+				}
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+	_, err := ParallelScan(bytes.NewReader(testData), int64(len(testData)), 4, scan, WithWindowSize(16))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error %s: got %v, want the scan function's error", title, err)
+	}
+	if n := cancelled.Load(); n != 3 {
+		t.Errorf("Error %s: %d of the other 3 regions were cancelled", title, n)
+	}
+}
@@ -0,0 +1,98 @@
+package hashbuffer
+
+import (
+	"log/slog"
+)
+
+// Peek returns up to n bytes from the start of the current window, the one Offset() reports, without moving
+// forward; before the first window, they are from the start of the stream.  It reads more of the stream if
+// they are not all buffered yet, first growing the buffer if n is larger than it.  Fewer than n bytes are
+// returned at the end of the stream (with no error), or along with a read error.  The data is only valid
+// until the next call of any method.
+func (ahb *abstractHashBuffer) Peek(n int) (data []byte, err error) {
+	if ahb.closed {
+		err = ErrClosed
+		return
+	}
+	err = ahb.cancelled()
+	if err != nil || n <= 0 {
+		return
+	}
+	if ahb.strategy == RingBuffer {
+		ahb.wrapRing()
+	}
+	// the pointer is one past the start of the current window
+	start := ahb.pointer - 1
+	if ahb.Offset() < 0 {
+		// before the first window, from the start of the stream
+		start = 0
+	} else if start < 0 {
+		// compacting or wrapping the buffer left the first byte of the current window only in preceding
+		ahb.rebase(start, n)
+		start = 0
+	}
+	if start+n > ahb.fillLevel && ahb.isOpen {
+		// a ring has room for as much as it holds after the start of the current window
+		if ahb.strategy != RingBuffer || n > ahb.bufferSize {
+			// move the current window to the beginning, to make room for n bytes after its start
+			ahb.rebase(start, n)
+			start = 0
+		}
+		err = ahb.readUntil(start + n)
+	}
+	end := min(start+n, ahb.fillLevel)
+	if end > len(ahb.buffer) {
+		// the data goes round the end of the ring, past the mirror
+		ahb.rebase(start, n)
+		start, end = 0, end-start
+	}
+	data = ahb.buffer[start:end]
+	return
+}
+
+// rebase moves the data from index start to the beginning of the buffer, first growing the buffer if it
+// holds fewer than n bytes.  The pointer and the other indexes move with the data.  A start of -1 is the
+// byte in preceding, which is put back at the beginning.
+func (ahb *abstractHashBuffer) rebase(start int, n int) {
+	// the byte from preceding, if any, goes in front of the data
+	shift := 0
+	if start < 0 {
+		shift, start = 1, 0
+	}
+	size := max(ahb.bufferSize, n, ahb.fillLevel-start+shift)
+	preceding := ahb.preceding
+	if start > 0 {
+		ahb.preceding = ahb.buffer[start-1]
+		ahb.hasPreceding = true
+	} else if shift > 0 {
+		// the byte before that one is gone
+		ahb.preceding = 0
+		ahb.hasPreceding = false
+	}
+	buffer := ahb.buffer
+	if ahb.strategy == RingBuffer {
+		// copied out of the ring in two parts, into a new ring that starts with it
+		buffer = make([]byte, size+ahb.windowSize-1)
+		copied := copy(buffer[shift:], ahb.buffer[start:min(ahb.fillLevel, ahb.bufferSize)])
+		if ahb.fillLevel > ahb.bufferSize {
+			copy(buffer[shift+copied:], ahb.buffer[:ahb.fillLevel-ahb.bufferSize])
+		}
+	} else {
+		if size > ahb.bufferSize {
+			buffer = make([]byte, size)
+		}
+		copy(buffer[shift:], ahb.buffer[start:ahb.fillLevel])
+	}
+	if shift > 0 {
+		buffer[0] = preceding
+	}
+	ahb.logf(slog.LevelDebug, "Rebasing buffer  from %d  fillLevel %d  size %d", start-shift, ahb.fillLevel, size)
+	ahb.buffer = buffer
+	ahb.bufferSize = size
+	ahb.bufferOffset += int64(start - shift)
+	ahb.fillLevel -= start - shift
+	ahb.pointer -= start - shift
+	if ahb.strategy == RingBuffer {
+		copy(ahb.buffer[size:], ahb.buffer[:min(ahb.fillLevel, ahb.windowSize-1)])
+	}
+}
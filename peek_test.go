@@ -0,0 +1,148 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+// Make sure Peek() returns the data from the start of the current window, around refills and beyond the
+// buffer, without moving forward, for both buffer strategies.
+func TestPeek(t *testing.T) {
+	readers := map[string]func([]byte) io.Reader{
+		"file": func(data []byte) io.Reader { return onlyReader{bytes.NewReader(data)} },
+		"half": func(data []byte) io.Reader { return iotest.HalfReader(bytes.NewReader(data)) },
+	}
+	for _, size := range []int{1023, 1024, 1025, len(testData)} {
+		for _, strategy := range []BufferStrategy{CompactBuffer, RingBuffer} {
+			for name, reader := range readers {
+				title := fmt.Sprintf("TestPeek_%s_%d_%d", name, size, strategy)
+				hb, err := NewReader(reader(testData[:size]), WithBufferSize(1024), WithWindowSize(16),
+					WithBufferStrategy(strategy))
+				check(t, err)
+				// before the first window, from the start of the stream
+				testPeek(t, hb, title, testData[:size], 0, 16)
+				offset := 0
+				for _, step := range []int{0, 990, 8, 1, 1, 1, 1, 20, 3000} {
+					// room for the window and the one rolled to after it
+					if offset+step+17 > size {
+						break
+					}
+					if step > 1 {
+						_, err = hb.Skip(step - 1)
+						check(t, err)
+					}
+					offset += step
+					testGet(t, hb, title, testData, offset)
+					for _, n := range []int{1, 16, 17, 24, 100, 1008, 1009, 1024, 1025, 2000} {
+						testPeek(t, hb, title, testData[:size], offset, n)
+					}
+					testOffset(t, hb, title, int64(offset))
+					// the roll still reports the byte leaving the window
+					in, out, ok, err := hb.GetRoll()
+					check(t, err)
+					offset++
+					if !ok || in != testData[offset+15] || out != testData[offset-1] {
+						t.Fatalf("Error %s: at offset %d got in %#x out %#x ok %t after Peek()", title, offset, in, out, ok)
+					}
+				}
+				testPeek(t, hb, title, testData[:size], offset, size)
+				for window, err := range hb.Windows() {
+					check(t, err)
+					offset++
+					if !testEq(window, testData[offset:offset+16]) {
+						t.Fatalf("Error %s: window at offset %d does not match after Peek()", title, offset)
+					}
+				}
+				if offset != size-16 {
+					t.Errorf("Error %s: windows ended at offset %d, want %d", title, offset, size-16)
+				}
+				closeTestHashBuffer(t, hb)
+			}
+		}
+	}
+}
+
+// Make sure Peek() after a Skip() that compacted or wrapped the buffer at the end of the stream still starts
+// at the current window, whose first byte was moved out of the buffer.
+func TestPeekAfterCompaction(t *testing.T) {
+	for _, test := range []struct {
+		strategy   BufferStrategy
+		size       int
+		bufferSize int
+		windowSize int
+	}{
+		{CompactBuffer, 1025, 1024, 16},
+		// the ring wraps with the pointer just at its end
+		{RingBuffer, 40, 32, 8},
+	} {
+		title := fmt.Sprintf("TestPeekAfterCompaction_%d", test.strategy)
+		data := testData[:test.size]
+		last := test.size - test.windowSize
+		hb, err := NewReader(onlyReader{bytes.NewReader(data)}, WithBufferSize(test.bufferSize),
+			WithWindowSize(test.windowSize), WithBufferStrategy(test.strategy))
+		check(t, err)
+		testGet(t, hb, title, data, 0)
+		_, err = hb.Skip(5000)
+		check(t, err)
+		testOffset(t, hb, title, int64(last-1))
+		testPeek(t, hb, title, data, last-1, test.windowSize)
+		testPeek(t, hb, title, data, last-1, 100)
+		testOffset(t, hb, title, int64(last-1))
+		in, out, ok, err := hb.GetRoll()
+		check(t, err)
+		if !ok || in != data[test.size-1] || out != data[last-1] {
+			t.Errorf("Error %s: got in %#x out %#x ok %t after Peek()", title, in, out, ok)
+		}
+		testGetZero(t, hb, title)
+		closeTestHashBuffer(t, hb)
+	}
+}
+
+// Make sure Peek() of a stream shorter than the window, and of in-memory data, returns the data there is.
+func TestPeekShort(t *testing.T) {
+	for _, size := range []int{0, 1, 15} {
+		title := fmt.Sprintf("TestPeekShort_%d", size)
+		hb, err := NewReaderHashBuffer(bytes.NewReader(testData[:size]), 64, 16)
+		check(t, err)
+		testPeek(t, hb, title, testData[:size], 0, 100)
+		testGet(t, hb, title, testData, 0)
+		testPeek(t, hb, title, testData[:size], 0, 100)
+		closeTestHashBuffer(t, hb)
+	}
+
+	hb, err := NewBytesHashBuffer(testData[:1025], 16)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	testPeek(t, hb, "TestPeekShort_bytes", testData[:1025], 0, 2000)
+	// as 1000 calls of GetNext(), so the current window is the 1000th
+	_, err = hb.Skip(1000)
+	check(t, err)
+	testPeek(t, hb, "TestPeekShort_bytes", testData[:1025], 999, 2000)
+}
+
+// Make sure Peek() reports a closed HashBuffer.
+func TestPeekClosed(t *testing.T) {
+	hb, err := NewReaderHashBuffer(bytes.NewReader(testData), 64, 16)
+	check(t, err)
+	closeTestHashBuffer(t, hb)
+	if _, err = hb.Peek(16); err != ErrClosed {
+		t.Errorf("Error TestPeekClosed: got %v, want ErrClosed", err)
+	}
+}
+
+// testPeek peeks n bytes, which should be those of data from offset, without moving forward.
+func testPeek(t *testing.T, hb HashBuffer, title string, data []byte, offset int, n int) {
+	t.Helper()
+	before := hb.Offset()
+	peeked, err := hb.Peek(n)
+	check(t, err)
+	if want := data[offset:min(offset+n, len(data))]; !testEq(peeked, want) && len(want) > 0 {
+		t.Fatalf("Error %s: Peek(%d) at offset %d got %d bytes, want %d", title, n, offset, len(peeked), len(want))
+	}
+	if after := hb.Offset(); after != before {
+		t.Errorf("Error %s: Peek(%d) moved from offset %d to %d", title, n, before, after)
+	}
+}
@@ -0,0 +1,123 @@
+package hashbuffer
+
+import (
+	"context"
+	"io"
+)
+
+// readAheadReader reads the stream on a goroutine, into one of two buffers, while the HashBuffer copies the
+// data out of the other.  At most two buffers are ever in use, so the goroutine waits once it is two reads ahead.
+type readAheadReader struct {
+	// buffers that have been filled, with the error of the read that filled them, in stream order
+	full chan readAheadChunk
+	// buffers that have been used up, for the goroutine to fill again
+	free chan []byte
+	// closed by cancel() to tell the goroutine to stop
+	done chan struct{}
+	// closed by the goroutine when it returns
+	exited chan struct{}
+	// the HashBuffer's context (WithContext), or nil; Read returns its error rather than wait once it is done
+	ctx context.Context
+	// the chunk being copied out, and how much of it has been
+	current readAheadChunk
+	copied  int
+}
+
+// readAheadChunk is the result of one read of the stream.
+type readAheadChunk struct {
+	data []byte
+	err  error
+}
+
+// newReadAheadReader starts reading reader on a goroutine, in reads of up to size bytes.  ctx may be nil.
+func newReadAheadReader(reader io.Reader, size int, ctx context.Context) (r *readAheadReader) {
+	r = &readAheadReader{
+		full:   make(chan readAheadChunk, 1),
+		free:   make(chan []byte, 2),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+		ctx:    ctx,
+	}
+	r.free <- make([]byte, size)
+	r.free <- make([]byte, size)
+	go r.run(reader)
+	return
+}
+
+// run reads the stream into free buffers and passes them on, until the stream ends, fails or cancel() is called.
+func (r *readAheadReader) run(reader io.Reader) {
+	defer close(r.exited)
+	for {
+		var buffer []byte
+		select {
+		case buffer = <-r.free:
+		case <-r.done:
+			return
+		}
+		var chunk readAheadChunk
+		// as fillBuffer does, give up on a reader that keeps returning nothing
+		for emptyReads := 0; len(chunk.data) == 0 && chunk.err == nil; emptyReads++ {
+			if emptyReads == maxEmptyReads {
+				chunk.err = io.ErrNoProgress
+				break
+			}
+			var n int
+			n, chunk.err = reader.Read(buffer[:cap(buffer)])
+			chunk.data = buffer[:n]
+		}
+		select {
+		case r.full <- chunk:
+		case <-r.done:
+			return
+		}
+		if chunk.err != nil {
+			return
+		}
+	}
+}
+
+// Read copies data already read by the goroutine, waiting for it if there is none.
+func (r *readAheadReader) Read(p []byte) (n int, err error) {
+	if r.copied == len(r.current.data) {
+		if r.current.err != nil {
+			return 0, r.current.err
+		}
+		if r.current.data != nil {
+			// never blocks: there is room for both buffers
+			r.free <- r.current.data
+		}
+		// a nil channel never receives, so without a context this only waits for the goroutine
+		var cancelled <-chan struct{}
+		if r.ctx != nil {
+			cancelled = r.ctx.Done()
+		}
+		select {
+		case r.current = <-r.full:
+		case <-cancelled:
+			// the buffer went back to the goroutine, so start the next Read from nothing
+			r.current, r.copied = readAheadChunk{}, 0
+			return 0, r.ctx.Err()
+		}
+		r.copied = 0
+	}
+	n = copy(p, r.current.data[r.copied:])
+	r.copied += n
+	if r.copied == len(r.current.data) {
+		err = r.current.err
+	}
+	return
+}
+
+// cancel tells the goroutine to stop once any read in progress returns.
+func (r *readAheadReader) cancel() {
+	select {
+	case <-r.done:
+	default:
+		close(r.done)
+	}
+}
+
+// wait waits for the goroutine to return.
+func (r *readAheadReader) wait() {
+	<-r.exited
+}
@@ -0,0 +1,186 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// Make sure read-ahead returns the same windows and rolls, with both buffer strategies and short reads.
+func TestReadAhead(t *testing.T) {
+	for _, size := range []int{0, 1, 16, 1023, 1024, 1025, len(testData)} {
+		for _, strategy := range []BufferStrategy{CompactBuffer, RingBuffer} {
+			title := fmt.Sprintf("TestReadAhead_%d_%d", size, strategy)
+			hb, err := NewReader(iotest.HalfReader(bytes.NewReader(testData[:size])), WithBufferSize(64),
+				WithWindowSize(16), WithBufferStrategy(strategy), WithReadAhead(true))
+			check(t, err)
+			testRingWindows(t, hb, title, testData[:size], 16)
+			closeTestHashBuffer(t, hb)
+		}
+	}
+}
+
+// Make sure Skip() reads through the data of a file rather than seeking it, which the goroutine has read past.
+func TestReadAheadSkip(t *testing.T) {
+	const title = "TestReadAheadSkip"
+
+	hb, err := OpenFile("./testdata/data_long", WithBufferSize(1024), WithWindowSize(16), WithReadAhead(true))
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	testGet(t, hb, title, testData, 0)
+	skipped, err := hb.Skip(5000)
+	check(t, err)
+	if skipped != 5000 {
+		t.Fatalf("Error %s: skipped %d, should have skipped 5000", title, skipped)
+	}
+	testGet(t, hb, title, testData, 5001)
+	testOffset(t, hb, title, 5001)
+}
+
+// Make sure strides and skips with read-ahead, which hands over partial buffers, go on to the end of the stream.
+func TestReadAheadStride(t *testing.T) {
+	for _, windowSize := range []int{1, 3, 7, 17, 63} {
+		for _, stride := range []int{2, 5, windowSize, 100} {
+			title := fmt.Sprintf("TestReadAheadStride_%d_%d", windowSize, stride)
+			hb, err := OpenFile("./testdata/data_long", WithWindowSize(windowSize), WithBufferSize(64+windowSize),
+				WithReadAhead(true), WithStride(stride))
+			check(t, err)
+			count := 0
+			for window, err := range hb.Windows() {
+				check(t, err)
+				if !testEq(window, testData[count*stride:count*stride+windowSize]) {
+					t.Fatalf("Error %s: window %d does not match", title, count)
+				}
+				count++
+			}
+			if want := (len(testData)-windowSize)/stride + 1; count != want {
+				t.Errorf("Error %s: got %d windows, want %d", title, count, want)
+			}
+			closeTestHashBuffer(t, hb)
+		}
+		title := fmt.Sprintf("TestReadAheadStride_skip_%d", windowSize)
+		hb, err := OpenFile("./testdata/data_long", WithWindowSize(windowSize), WithBufferSize(64+windowSize),
+			WithReadAhead(true))
+		check(t, err)
+		testGet(t, hb, title, testData, 0)
+		skipped, err := hb.Skip(1 << 40)
+		check(t, err)
+		if want := len(testData) - windowSize - 1; skipped != want {
+			t.Errorf("Error %s: skipped %d, should have skipped %d", title, skipped, want)
+		}
+		testGet(t, hb, title, testData, len(testData)-windowSize)
+		testGetZero(t, hb, title)
+		closeTestHashBuffer(t, hb)
+	}
+}
+
+// Make sure a read error on the goroutine is returned, as a *ReadError at the right offset, after the data before it.
+func TestReadAheadError(t *testing.T) {
+	const title = "TestReadAheadError"
+
+	reader := io.MultiReader(bytes.NewReader(testData[:1000]), iotest.ErrReader(errTestRead))
+	hb, err := NewReader(reader, WithBufferSize(64), WithWindowSize(16), WithReadAhead(true))
+	check(t, err)
+	defer hb.Close()
+	windows := 0
+	for _, err = range hb.Windows() {
+		if err != nil {
+			break
+		}
+		windows++
+	}
+	var readError *ReadError
+	if !errors.As(err, &readError) || !errors.Is(err, errTestRead) || readError.Offset != 1000 {
+		t.Errorf("Error %s: got %v, want a *ReadError at offset 1000", title, err)
+	}
+	if windows != 1000-15 {
+		t.Errorf("Error %s: got %d windows before the error, want %d", title, windows, 1000-15)
+	}
+}
+
+// Make sure the goroutine stops reading once it is two buffers ahead.
+func TestReadAheadBounded(t *testing.T) {
+	const title = "TestReadAheadBounded"
+
+	// an endless stream
+	reader := &testCountingReader{Reader: rand.New(rand.NewSource(1))}
+	hb, err := NewReader(reader, WithBufferSize(1024), WithWindowSize(16), WithReadAhead(true))
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	_, err = hb.GetWindow()
+	check(t, err)
+	// give the goroutine time to read as far as it would
+	time.Sleep(20 * time.Millisecond)
+	if read := reader.read.Load(); read > 2*1024 {
+		t.Errorf("Error %s: read %d bytes ahead, want at most %d", title, read, 2*1024)
+	}
+}
+
+// Make sure a Read after one cancelled while waiting for the goroutine returns the context's error again,
+// rather than copying from the buffer it gave back.
+func TestReadAheadCancelledRead(t *testing.T) {
+	const title = "TestReadAheadCancelledRead"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// the reader blocks after its data, so the second Read waits for the goroutine
+	source := newSlowReader(testData[:8], time.Millisecond)
+	defer source.Close()
+	r := newReadAheadReader(source, 64, ctx)
+	defer func() {
+		r.cancel()
+		source.Close()
+		r.wait()
+	}()
+	p := make([]byte, 64)
+	n, err := r.Read(p)
+	check(t, err)
+	if !testEq(p[:n], testData[:8]) {
+		t.Fatalf("Error %s: read %#x, want %#x", title, p[:n], testData[:8])
+	}
+	cancel()
+	for i := 0; i < 2; i++ {
+		if _, err = r.Read(p); !errors.Is(err, context.Canceled) {
+			t.Errorf("Error %s: Read() %d got %v, want context.Canceled", title, i, err)
+		}
+	}
+}
+
+// Make sure Close() stops a goroutine blocked reading, by closing the stream, and closes it once.
+func TestReadAheadClose(t *testing.T) {
+	const title = "TestReadAheadClose"
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		// nothing more is written, so the goroutine is left waiting for data
+		pipeWriter.Write(testData[:100])
+	}()
+	hb, err := NewReader(pipeReader, WithBufferSize(64), WithWindowSize(16), WithReadAhead(true))
+	check(t, err)
+	testGet(t, hb, title, testData, 0)
+	readAhead := hb.(*readerHashBuffer).readAhead
+	closed := make(chan error)
+	go func() {
+		closed <- hb.Close()
+	}()
+	select {
+	case err = <-closed:
+		check(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Error %s: Close() did not return", title)
+	}
+	select {
+	case <-readAhead.exited:
+	default:
+		t.Errorf("Error %s: the goroutine is still running after Close()", title)
+	}
+	testClosed(t, hb, title)
+	if _, err = pipeWriter.Write(testData); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Error %s: the pipe is still open: %v", title, err)
+	}
+}
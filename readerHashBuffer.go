@@ -0,0 +1,41 @@
+package hashbuffer
+
+import (
+	"io"
+)
+
+// readerHashBuffer is an io.Reader based HashBuffer.
+type readerHashBuffer struct {
+	*abstractHashBuffer
+}
+
+// NewReaderHashBuffer creates a HashBuffer against the specified io.Reader, with the specified buffersize.
+// If reader also implements io.Closer, Close() will close it; otherwise Close() only closes the HashBuffer.
+func NewReaderHashBuffer(reader io.Reader, bufferSize int, windowSize int) (hashBuffer HashBuffer, err error) {
+	return NewReader(reader, positionalOptions(bufferSize, windowSize)...)
+}
+
+// NewReader creates a HashBuffer against the specified io.Reader, configured by options; WithWindowSize is required.
+// If reader also implements io.Closer, Close() will close it; otherwise Close() only closes the HashBuffer.
+func NewReader(reader io.Reader, options ...Option) (hashBuffer HashBuffer, err error) {
+	o, err := newOptions(options, false)
+	if err != nil {
+		return
+	}
+	rhb := new(readerHashBuffer)
+	hashBuffer = rhb
+	rhb.abstractHashBuffer = new(abstractHashBuffer)
+
+	closer, ok := reader.(io.Closer)
+	if !ok {
+		closer = nopCloser{}
+	}
+	rhb.abstractHashBuffer.isOpen = true
+	rhb.abstractHashBuffer.init(reader, closer, o)
+	return
+}
+
+// nopCloser is used for readers that have nothing to close.
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
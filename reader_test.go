@@ -0,0 +1,109 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// Make sure calling GetWindow() and GetNext() on an empty reader works as expected.
+func TestReaderEmpty(t *testing.T) {
+	const title = "TestReaderEmpty"
+
+	t.Logf("start %s", title)
+	hb := newTestReaderHashBuffer(t, 0)
+	defer closeTestHashBuffer(t, hb)
+	testGetZero(t, hb, fmt.Sprintf("%s first round", title))
+	testGetZero(t, hb, fmt.Sprintf("%s second round", title))
+	testGetNextZero(t, hb, fmt.Sprintf("%s third round", title))
+}
+
+// Make sure calling GetWindow() on a 1-byte reader works as expected.
+func TestReaderOneByte(t *testing.T) {
+	const title = "TestReaderOneByte"
+
+	t.Logf("start %s", title)
+	hb := newTestReaderHashBuffer(t, 1)
+	defer closeTestHashBuffer(t, hb)
+	testGet(t, hb, fmt.Sprintf("%s first round", title), testData, 0)
+	testGetZero(t, hb, fmt.Sprintf("%s second round", title))
+	testGetNextZero(t, hb, fmt.Sprintf("%s third round", title))
+}
+
+// Make sure GetWindow() and GetNext() on various size readers return the exact same data that went in.
+func TestReaderCompareSizes(t *testing.T) {
+	for _, size := range []int{15, 16, 17, 1023, 1024, 1025, len(testData)} {
+		testReaderCompareWithGet(t, fmt.Sprintf("TestReader_%d_WithGet", size), size)
+		testReaderCompareWithGetNext(t, fmt.Sprintf("TestReader_%d_WithGetNext", size), size)
+	}
+}
+
+// Test that Skip() works the same way against a reader as it does against a file.
+func TestReaderSkip(t *testing.T) {
+	const title = "TestReaderSkip"
+
+	t.Logf("start %s", title)
+	hb := newTestReaderHashBuffer(t, 1025)
+	defer closeTestHashBuffer(t, hb)
+	skipped, err := hb.Skip(1010)
+	check(t, err)
+	if skipped != 1009 {
+		t.Errorf("Error %s: skipped %d, should have skipped 1009", title, skipped)
+	}
+	testGet(t, hb, title, testData, 1009)
+	testGetZero(t, hb, title)
+}
+
+func testReaderCompareWithGet(t *testing.T, title string, expectedSize int) {
+	const windowSize = 16
+
+	t.Logf("start %s", title)
+	compareBuffer := make([]byte, expectedSize)
+	hb := newTestReaderHashBuffer(t, expectedSize)
+	defer closeTestHashBuffer(t, hb)
+	buf := testGet(t, hb, fmt.Sprintf("%s first round", title), testData, 0)
+	copy(compareBuffer, buf)
+	for i := windowSize; i <= (expectedSize - 1); i++ {
+		buf = testGet(t, hb, fmt.Sprintf("%s second round", title), testData, i-windowSize+1)
+		compareBuffer[i] = buf[len(buf)-1]
+	}
+	testGetNextZero(t, hb, fmt.Sprintf("%s third round", title))
+	if !testEq(compareBuffer, testData[0:expectedSize]) {
+		t.Errorf("Error %s read doesn't match test data  size %d", title, expectedSize)
+	}
+}
+
+func testReaderCompareWithGetNext(t *testing.T, title string, expectedSize int) {
+	const windowSize = 16
+
+	t.Logf("start %s", title)
+	compareBuffer := make([]byte, expectedSize)
+	hb := newTestReaderHashBuffer(t, expectedSize)
+	defer closeTestHashBuffer(t, hb)
+	buf := testGet(t, hb, fmt.Sprintf("%s first round", title), testData, 0)
+	copy(compareBuffer, buf)
+	for i := windowSize; i <= (expectedSize - 1); i++ {
+		outByte, _ := testGetNextOne(t, hb, fmt.Sprintf("%s second round", title), testData[i])
+		compareBuffer[i] = outByte
+	}
+	testGetNextZero(t, hb, fmt.Sprintf("%s third round", title))
+	if !testEq(compareBuffer, testData[0:expectedSize]) {
+		t.Errorf("Error %s read doesn't match test data  size %d", title, expectedSize)
+	}
+}
+
+// newTestReaderHashBuffer returns a HashBuffer over the first size bytes of testData.
+func newTestReaderHashBuffer(t *testing.T, size int) HashBuffer {
+	const bufferSize = 1024
+	const windowSize = 16
+
+	hb, err := NewReaderHashBuffer(bytes.NewReader(testData[:size]), bufferSize, windowSize)
+	check(t, err)
+	return hb
+}
+
+func closeTestHashBuffer(t *testing.T, hb HashBuffer) {
+	t.Log("Closing")
+	err := hb.Close()
+	check(t, err)
+}
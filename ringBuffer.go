@@ -0,0 +1,68 @@
+package hashbuffer
+
+import (
+	"log/slog"
+)
+
+// BufferStrategy selects how the buffer of a HashBuffer reading a stream makes room for more data.
+type BufferStrategy int
+
+const (
+	// CompactBuffer, the default, moves the data from the start of the current window to the beginning of the
+	// buffer each time it is refilled.  Each refill copies at least windowSize-1 bytes.
+	CompactBuffer BufferStrategy = iota
+	// RingBuffer reads into the buffer as a ring, so nothing is moved when it is refilled.  The first
+	// windowSize-1 bytes of the ring are mirrored just past its end, so a window that wraps around is still
+	// contiguous; that is the only copy, once for each time round the ring.  This is worthwhile for large windows.
+	RingBuffer
+)
+
+// The ring layout: the ring is buffer[:bufferSize], and buffer[bufferSize:] mirrors the start of the ring.
+// pointer and fillLevel are kept as if the buffer never wrapped, relative to bufferOffset, so a stream offset
+// is still bufferOffset plus an index; the data of index i (for i < bufferSize+windowSize-1) is at buffer[i].
+// Once the pointer passes the end of the ring, wrapRing moves bufferOffset on by a whole ring.
+
+// initRing allocates the ring and its mirror.
+func (ahb *abstractHashBuffer) initRing() {
+	// besides the window and the byte before it, which GetRoll reports, there must be room to read more, or
+	// Skip and strides would stall; twice the window size means each refill reads at least a window's worth
+	ahb.bufferSize = max(ahb.bufferSize, 2*ahb.windowSize, ahb.windowSize+2)
+	ahb.buffer = make([]byte, ahb.bufferSize+ahb.windowSize-1)
+}
+
+// wrapRing moves the indexes back by the size of the ring once the pointer has passed its end.
+// Nothing is copied: the data stays in the same slots of the ring.
+func (ahb *abstractHashBuffer) wrapRing() {
+	if ahb.pointer < ahb.bufferSize {
+		return
+	}
+	// only needed (and only still there) when the pointer is exactly at the end
+	ahb.preceding = ahb.buffer[ahb.bufferSize-1]
+	ahb.hasPreceding = true
+	ahb.pointer -= ahb.bufferSize
+	ahb.fillLevel -= ahb.bufferSize
+	ahb.bufferOffset += int64(ahb.bufferSize)
+	ahb.logf(slog.LevelDebug, "Wrapped the ring  pointer %d  fillLevel %d", ahb.pointer, ahb.fillLevel)
+}
+
+// ringSpace returns the free slots of the ring after fillLevel, up to the end of the ring.
+// The slots from the start of the current window (the byte before the pointer) on are in use.
+func (ahb *abstractHashBuffer) ringSpace() []byte {
+	limit := max(ahb.pointer-1, 0) + ahb.bufferSize
+	if ahb.fillLevel >= limit {
+		return nil
+	}
+	slot := ahb.fillLevel
+	if slot >= ahb.bufferSize {
+		slot -= ahb.bufferSize
+	}
+	return ahb.buffer[slot:min(ahb.bufferSize, slot+limit-ahb.fillLevel)]
+}
+
+// mirrorRing copies data just read into the ring to the mirror, where it falls in the first windowSize-1 slots.
+func (ahb *abstractHashBuffer) mirrorRing(data []byte) {
+	slot := cap(ahb.buffer) - cap(data)
+	if mirrored := len(ahb.buffer) - ahb.bufferSize; slot < mirrored {
+		copy(ahb.buffer[ahb.bufferSize+slot:], data[:min(len(data), mirrored-slot)])
+	}
+}
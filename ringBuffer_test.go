@@ -0,0 +1,199 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+)
+
+// onlyReader hides every method of a reader but Read, so Skip() has to read through the data.
+type onlyReader struct {
+	io.Reader
+}
+
+// Make sure the ring buffer returns the same windows and rolls as the data, for windows small and large
+// compared to the buffer, and for readers that return less than asked for.
+func TestRingBuffer(t *testing.T) {
+	readers := map[string]func([]byte) io.Reader{
+		"bytes":   func(data []byte) io.Reader { return bytes.NewReader(data) },
+		"onebyte": func(data []byte) io.Reader { return iotest.OneByteReader(bytes.NewReader(data)) },
+		"half":    func(data []byte) io.Reader { return iotest.HalfReader(bytes.NewReader(data)) },
+	}
+	for _, size := range []int{0, 1, 16, 17, 1023, 1024, 1025, len(testData)} {
+		for _, sizes := range [][2]int{{1024, 16}, {17, 16}, {1024, 1000}, {64, 1}} {
+			for name, reader := range readers {
+				title := fmt.Sprintf("TestRingBuffer_%s_%d_%d_%d", name, size, sizes[0], sizes[1])
+				hb, err := NewReader(reader(testData[:size]), WithBufferSize(sizes[0]), WithWindowSize(sizes[1]),
+					WithBufferStrategy(RingBuffer))
+				check(t, err)
+				testRingWindows(t, hb, title, testData[:size], sizes[1])
+				closeTestHashBuffer(t, hb)
+			}
+		}
+	}
+}
+
+// Make sure Skip() across the end of the ring, by amounts of up to several rings, lands on the right window.
+func TestRingBufferSkip(t *testing.T) {
+	const title = "TestRingBufferSkip"
+
+	random := rand.New(rand.NewSource(1))
+	hb, err := NewReader(onlyReader{bytes.NewReader(testData)}, WithBufferSize(100), WithWindowSize(16),
+		WithBufferStrategy(RingBuffer))
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	testGet(t, hb, title, testData, 0)
+	offset := 0
+	for offset+16 < len(testData) {
+		count := random.Intn(300)
+		skipped, err := hb.Skip(count)
+		check(t, err)
+		offset += skipped
+		in, out, ok, err := hb.GetRoll()
+		check(t, err)
+		if !ok {
+			break
+		}
+		offset++
+		if in != testData[offset+15] || out != testData[offset-1] {
+			t.Fatalf("Error %s: at offset %d got in %#x out %#x, want in %#x out %#x",
+				title, offset, in, out, testData[offset+15], testData[offset-1])
+		}
+		testOffset(t, hb, title, int64(offset))
+	}
+	if offset != len(testData)-16 {
+		t.Errorf("Error %s: ended at offset %d, want %d", title, offset, len(testData)-16)
+	}
+}
+
+// Make sure a ring just larger than the window still moves on with Skip() and strides.
+func TestRingBufferSmall(t *testing.T) {
+	for _, windowSize := range []int{1, 2, 16} {
+		for _, bufferSize := range []int{windowSize + 1, windowSize + 2} {
+			title := fmt.Sprintf("TestRingBufferSmall_%d_%d", windowSize, bufferSize)
+			hb, err := NewReader(onlyReader{bytes.NewReader(testData)}, WithBufferSize(bufferSize),
+				WithWindowSize(windowSize), WithBufferStrategy(RingBuffer))
+			check(t, err)
+			testGet(t, hb, title, testData, 0)
+			skipped, err := hb.Skip(1 << 40)
+			check(t, err)
+			if want := len(testData) - windowSize - 1; skipped != want {
+				t.Errorf("Error %s: skipped %d, should have skipped %d", title, skipped, want)
+			}
+			closeTestHashBuffer(t, hb)
+
+			hb, err = NewReader(onlyReader{bytes.NewReader(testData)}, WithBufferSize(bufferSize),
+				WithWindowSize(windowSize), WithBufferStrategy(RingBuffer), WithStride(windowSize+1))
+			check(t, err)
+			count := 0
+			for window, err := range hb.Windows() {
+				check(t, err)
+				if start := count * (windowSize + 1); !testEq(window, testData[start:start+windowSize]) {
+					t.Fatalf("Error %s: window %d does not match", title, count)
+				}
+				count++
+			}
+			if want := (len(testData)-windowSize)/(windowSize+1) + 1; count != want {
+				t.Errorf("Error %s: got %d windows with a stride, want %d", title, count, want)
+			}
+			closeTestHashBuffer(t, hb)
+		}
+	}
+}
+
+// Make sure the ring buffer is used by the file HashBuffer as well, and a stride works with it.
+func TestRingBufferFileStride(t *testing.T) {
+	const title = "TestRingBufferFileStride"
+
+	hb, err := OpenFile("./testdata/data_long", WithBufferSize(1024), WithWindowSize(600), WithStride(600),
+		WithBufferStrategy(RingBuffer))
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	i := 0
+	for ; i+600 <= len(testData); i += 600 {
+		window := testGet(t, hb, title, testData, i)
+		if len(window) != 600 {
+			t.Fatalf("Error %s: got a window of %d bytes at offset %d", title, len(window), i)
+		}
+	}
+	testGetZero(t, hb, title)
+}
+
+// testRingWindows reads every window with GetWindow() and GetRoll() in turn, comparing them to the data.
+func testRingWindows(t *testing.T, hb HashBuffer, title string, data []byte, windowSize int) {
+	windowSize = min(windowSize, len(data))
+	window, err := hb.GetWindow()
+	check(t, err)
+	if !testEq(window, data[:windowSize]) && len(data) > 0 {
+		t.Fatalf("Error %s: first window does not match", title)
+	}
+	for i := 1; i+windowSize <= len(data); i++ {
+		if i%2 == 0 {
+			window, err = hb.GetWindow()
+			check(t, err)
+			if !testEq(window, data[i:i+windowSize]) {
+				t.Fatalf("Error %s: window at offset %d does not match", title, i)
+			}
+		} else {
+			in, out, ok, err := hb.GetRoll()
+			check(t, err)
+			if !ok || in != data[i+windowSize-1] || out != data[i-1] {
+				t.Fatalf("Error %s: at offset %d got in %#x out %#x ok %t, want in %#x out %#x",
+					title, i, in, out, ok, data[i+windowSize-1], data[i-1])
+			}
+		}
+	}
+	testGetZero(t, hb, title)
+}
+
+// Compare the throughput of the buffer strategies, sliding one byte at a time and by whole windows.
+func BenchmarkBufferStrategy(b *testing.B) {
+	data := make([]byte, 8<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+	strategies := map[string]BufferStrategy{"compact": CompactBuffer, "ring": RingBuffer}
+	for _, windowSize := range []int{16, 4096, 65536} {
+		for name, strategy := range strategies {
+			b.Run(fmt.Sprintf("GetNext/%s/%d", name, windowSize), func(b *testing.B) {
+				b.SetBytes(int64(len(data)))
+				for range b.N {
+					hb, err := NewReader(bytes.NewReader(data), WithWindowSize(windowSize),
+						WithBufferStrategy(strategy))
+					if err != nil {
+						b.Fatal(err)
+					}
+					for {
+						_, ok, err := hb.GetNext()
+						if err != nil {
+							b.Fatal(err)
+						}
+						if !ok {
+							break
+						}
+					}
+					hb.Close()
+				}
+			})
+			b.Run(fmt.Sprintf("Stride/%s/%d", name, windowSize), func(b *testing.B) {
+				b.SetBytes(int64(len(data)))
+				for range b.N {
+					// hide the Seeker, so the data is read rather than skipped
+					hb, err := NewReader(onlyReader{bytes.NewReader(data)}, WithWindowSize(windowSize),
+						WithStride(windowSize), WithBufferStrategy(strategy))
+					if err != nil {
+						b.Fatal(err)
+					}
+					for window, err := range hb.Windows() {
+						if err != nil {
+							b.Fatal(err)
+						}
+						_ = window
+					}
+					hb.Close()
+				}
+			})
+		}
+	}
+}
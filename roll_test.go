@@ -0,0 +1,77 @@
+package hashbuffer
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Make sure GetRoll() reports both ends of the slide, across buffer refills.
+func TestGetRoll(t *testing.T) {
+	testGetRoll(t, "./testdata/data_17", "TestGetRoll_17", 17)
+	testGetRoll(t, "./testdata/data_1023", "TestGetRoll_1023", 1023)
+	testGetRoll(t, "./testdata/data_1024", "TestGetRoll_1024", 1024)
+	testGetRoll(t, "./testdata/data_1025", "TestGetRoll_1025", 1025)
+	testGetRoll(t, "./testdata/data_long", "TestGetRoll_long", 35539)
+}
+
+// Make sure GetRoll() reports the byte just before the window after Skip() forces a refill.
+func TestGetRollAfterSkip(t *testing.T) {
+	const title = "TestGetRollAfterSkip"
+
+	hb, err := NewFileHashBuffer("./testdata/data_long", 1024, 16)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	testGet(t, hb, title, testData, 0)
+	skipped, err := hb.Skip(1500)
+	check(t, err)
+	if skipped != 1500 {
+		t.Fatalf("Error %s: skipped %d, should have skipped 1500", title, skipped)
+	}
+	in, out, ok, err := hb.GetRoll()
+	check(t, err)
+	if !ok || in != testData[1501+15] || out != testData[1500] {
+		t.Errorf("Error %s: got in %#x out %#x ok %t, want in %#x out %#x",
+			title, in, out, ok, testData[1501+15], testData[1500])
+	}
+}
+
+// Drive a polynomial rolling hash with GetRoll() and compare it to hashing each window from scratch.
+func testGetRoll(t *testing.T, filename string, title string, expectedSize int) {
+	const bufferSize = 1024
+	const windowSize = 16
+	const base = 257
+
+	t.Logf("start %s", title)
+	hb, err := NewFileHashBuffer(filename, bufferSize, windowSize)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	// base^(windowSize-1), the weight of the outgoing byte
+	var top uint32 = 1
+	for i := 1; i < windowSize; i++ {
+		top *= base
+	}
+	hash := func(window []byte) (h uint32) {
+		for _, b := range window {
+			h = h*base + uint32(b)
+		}
+		return
+	}
+	window := testGet(t, hb, fmt.Sprintf("%s first round", title), testData, 0)
+	rolling := hash(window)
+	for i := windowSize; i < expectedSize; i++ {
+		in, out, ok, err := hb.GetRoll()
+		check(t, err)
+		if !ok || in != testData[i] || out != testData[i-windowSize] {
+			t.Fatalf("Error %s: index %d got in %#x out %#x ok %t", title, i, in, out, ok)
+		}
+		rolling = (rolling-uint32(out)*top)*base + uint32(in)
+		if want := hash(testData[i-windowSize+1 : i+1]); rolling != want {
+			t.Fatalf("Error %s: index %d rolling hash %#x, want %#x", title, i, rolling, want)
+		}
+	}
+	_, _, ok, err := hb.GetRoll()
+	check(t, err)
+	if ok {
+		t.Errorf("Error %s: got ok=true past the end of the data", title)
+	}
+}
@@ -0,0 +1,39 @@
+package rolling
+
+// adler32Mod is the largest prime smaller than 65536.
+const adler32Mod = 65521
+
+// Adler32 is the Adler-32 checksum, as in hash/adler32, over a sliding window.
+// Sum64 returns the same value as adler32.Checksum(window).
+type Adler32 struct {
+	// the window size modulo adler32Mod, so that multiplying it by a byte cannot overflow
+	windowSize uint32
+	a, b       uint32
+}
+
+// NewAdler32 creates an Adler32 hash.
+func NewAdler32() *Adler32 {
+	return new(Adler32)
+}
+
+// Reset initializes the hash with the first window of data.
+func (ad *Adler32) Reset(window []byte) {
+	ad.windowSize = uint32(len(window) % adler32Mod)
+	ad.a, ad.b = 1, 0
+	for _, c := range window {
+		ad.a = (ad.a + uint32(c)) % adler32Mod
+		ad.b = (ad.b + ad.a) % adler32Mod
+	}
+}
+
+// Roll slides the window forward one byte.
+func (ad *Adler32) Roll(out byte, in byte) {
+	// a loses out and gains in; b loses windowSize copies of out (and the initial 1 it carried)
+	ad.a = (ad.a + adler32Mod - uint32(out) + uint32(in)) % adler32Mod
+	ad.b = (ad.b + adler32Mod - ad.windowSize*uint32(out)%adler32Mod + ad.a + adler32Mod - 1) % adler32Mod
+}
+
+// Sum64 returns the hash of the current window.
+func (ad *Adler32) Sum64() uint64 {
+	return uint64(ad.b)<<16 | uint64(ad.a)
+}
@@ -0,0 +1,39 @@
+package rolling
+
+import (
+	"math/bits"
+)
+
+// buzhashTable maps each byte to a pseudo-random value.
+var buzhashTable = byteTable(0x62757a68617368)
+
+// Buzhash is a cyclic polynomial rolling hash: each byte is mapped through a table of random values,
+// rotated by its distance from the end of the window, and the results are XORed together.
+type Buzhash struct {
+	windowSize int
+	hash       uint64
+}
+
+// NewBuzhash creates a Buzhash hash.
+func NewBuzhash() *Buzhash {
+	return new(Buzhash)
+}
+
+// Reset initializes the hash with the first window of data.
+func (bz *Buzhash) Reset(window []byte) {
+	bz.windowSize = len(window)
+	bz.hash = 0
+	for _, b := range window {
+		bz.hash = bits.RotateLeft64(bz.hash, 1) ^ buzhashTable[b]
+	}
+}
+
+// Roll slides the window forward one byte.
+func (bz *Buzhash) Roll(out byte, in byte) {
+	bz.hash = bits.RotateLeft64(bz.hash, 1) ^ bits.RotateLeft64(buzhashTable[out], bz.windowSize) ^ buzhashTable[in]
+}
+
+// Sum64 returns the hash of the current window.
+func (bz *Buzhash) Sum64() uint64 {
+	return bz.hash
+}
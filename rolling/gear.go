@@ -0,0 +1,45 @@
+package rolling
+
+// gearTable maps each byte to a pseudo-random value.
+var gearTable = byteTable(0x67656172)
+
+// Gear is the rolling hash used by FastCDC: the hash is shifted left one bit and a random value for
+// the incoming byte is added.  Bytes more than 64 positions back have shifted out entirely, so windows
+// of 64 bytes or more all hash the same as their last 64 bytes.
+type Gear struct {
+	windowSize int
+	hash       uint64
+}
+
+// NewGear creates a Gear hash.
+func NewGear() *Gear {
+	return new(Gear)
+}
+
+// GearTable returns the table of random values Gear uses, for algorithms that apply the hash directly.
+func GearTable() [256]uint64 {
+	return gearTable
+}
+
+// Reset initializes the hash with the first window of data.
+func (g *Gear) Reset(window []byte) {
+	g.windowSize = len(window)
+	g.hash = 0
+	for _, b := range window {
+		g.hash = g.hash<<1 + gearTable[b]
+	}
+}
+
+// Roll slides the window forward one byte.
+func (g *Gear) Roll(out byte, in byte) {
+	// remove what is left of the outgoing byte (nothing, once the window is 64 bytes or more)
+	g.hash = g.hash<<1 + gearTable[in]
+	if g.windowSize < 64 {
+		g.hash -= gearTable[out] << g.windowSize
+	}
+}
+
+// Sum64 returns the hash of the current window.
+func (g *Gear) Sum64() uint64 {
+	return g.hash
+}
@@ -0,0 +1,82 @@
+package rolling
+
+import (
+	"math/bits"
+)
+
+// DefaultRabinPolynomial is the irreducible polynomial of degree 53 over GF(2) used by NewRabin.
+const DefaultRabinPolynomial = 0x3DA3358B4DC173
+
+// Rabin is the Rabin fingerprint: each window of bytes, read as a polynomial over GF(2) with the bits of
+// b[0] as the highest terms, hashes to its remainder modulo an irreducible polynomial.
+type Rabin struct {
+	polynomial uint64
+	// degree of the polynomial; the fingerprint has that many bits
+	degree int
+	// modTable[b] reduces a fingerprint shifted left a byte, whose top byte is b
+	modTable [256]uint64
+	// outTable[b] is the fingerprint of b followed by the rest of a window of zeros, for removing b;
+	// it is computed for outWindowSize
+	outTable      [256]uint64
+	outWindowSize int
+	hash          uint64
+}
+
+// NewRabin creates a Rabin fingerprint using DefaultRabinPolynomial.
+func NewRabin() *Rabin {
+	return NewRabinPolynomial(DefaultRabinPolynomial)
+}
+
+// NewRabinPolynomial creates a Rabin fingerprint using the specified polynomial, which should be
+// irreducible, of degree 8 to 56, with bit i the coefficient of x^i.
+func NewRabinPolynomial(polynomial uint64) *Rabin {
+	rb := &Rabin{polynomial: polynomial, degree: bits.Len64(polynomial) - 1}
+	for b := range rb.modTable {
+		shifted := uint64(b) << rb.degree
+		rb.modTable[b] = polynomialMod(shifted, polynomial) | shifted
+	}
+	return rb
+}
+
+// polynomialMod returns the remainder of x divided by polynomial, over GF(2).
+func polynomialMod(x uint64, polynomial uint64) uint64 {
+	degree := bits.Len64(polynomial) - 1
+	for bits.Len64(x)-1 >= degree {
+		x ^= polynomial << (bits.Len64(x) - 1 - degree)
+	}
+	return x
+}
+
+// append multiplies the fingerprint by x^8 and adds b, modulo the polynomial.
+func (rb *Rabin) append(hash uint64, b byte) uint64 {
+	top := hash >> (rb.degree - 8)
+	return (hash<<8 | uint64(b)) ^ rb.modTable[top]
+}
+
+// Reset initializes the hash with the first window of data.
+func (rb *Rabin) Reset(window []byte) {
+	if len(window) != rb.outWindowSize {
+		rb.outWindowSize = len(window)
+		for b := range rb.outTable {
+			hash := rb.append(0, byte(b))
+			for i := 1; i < len(window); i++ {
+				hash = rb.append(hash, 0)
+			}
+			rb.outTable[b] = hash
+		}
+	}
+	rb.hash = 0
+	for _, b := range window {
+		rb.hash = rb.append(rb.hash, b)
+	}
+}
+
+// Roll slides the window forward one byte.
+func (rb *Rabin) Roll(out byte, in byte) {
+	rb.hash = rb.append(rb.hash^rb.outTable[out], in)
+}
+
+// Sum64 returns the hash of the current window.
+func (rb *Rabin) Sum64() uint64 {
+	return rb.hash
+}
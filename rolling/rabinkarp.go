@@ -0,0 +1,45 @@
+package rolling
+
+// DefaultRabinKarpBase is the multiplier used by NewRabinKarp.
+const DefaultRabinKarpBase = 0x100000001b3
+
+// RabinKarp is a polynomial rolling hash: each window of bytes b[0..n-1] hashes to
+// b[0]*base^(n-1) + b[1]*base^(n-2) + ... + b[n-1], modulo 2^64.
+type RabinKarp struct {
+	base uint64
+	// base^(n-1), the weight of the byte leaving the window
+	outWeight uint64
+	hash      uint64
+}
+
+// NewRabinKarp creates a RabinKarp hash using DefaultRabinKarpBase.
+func NewRabinKarp() *RabinKarp {
+	return NewRabinKarpBase(DefaultRabinKarpBase)
+}
+
+// NewRabinKarpBase creates a RabinKarp hash using the specified base, which should be odd.
+func NewRabinKarpBase(base uint64) *RabinKarp {
+	return &RabinKarp{base: base}
+}
+
+// Reset initializes the hash with the first window of data.
+func (rk *RabinKarp) Reset(window []byte) {
+	rk.hash = 0
+	rk.outWeight = 1
+	for i, b := range window {
+		rk.hash = rk.hash*rk.base + uint64(b)
+		if i > 0 {
+			rk.outWeight *= rk.base
+		}
+	}
+}
+
+// Roll slides the window forward one byte.
+func (rk *RabinKarp) Roll(out byte, in byte) {
+	rk.hash = (rk.hash-uint64(out)*rk.outWeight)*rk.base + uint64(in)
+}
+
+// Sum64 returns the hash of the current window.
+func (rk *RabinKarp) Sum64() uint64 {
+	return rk.hash
+}
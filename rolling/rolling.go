@@ -0,0 +1,75 @@
+// Package rolling provides rolling hashes that are driven by a hashbuffer.HashBuffer.
+//
+// Each hash is computed over a fixed-size window that slides forward one byte at a time;
+// the hash of the next window is built from the hash of the previous one, the byte leaving
+// the window and the byte entering it, as reported by HashBuffer.GetRoll().
+package rolling
+
+import (
+	"iter"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+)
+
+// RollingHasher defines a hash over a window of data that can be slid forward one byte at a time.
+type RollingHasher interface {
+	// Reset initializes the hash with the first window of data; its length is the window size from then on.
+	Reset(window []byte)
+	// Roll slides the window forward one byte: `out` leaves the window and `in` enters it.
+	Roll(out byte, in byte)
+	// Sum64 returns the hash of the current window.
+	Sum64() uint64
+}
+
+// Sum is the hash of one window, and the stream offset where the window starts.
+type Sum struct {
+	Offset int64
+	Hash   uint64
+}
+
+// Scan returns an iterator over the hash of every remaining window in hb, computed by rolling hasher.
+// If the stream is shorter than the window size of hb, the single short window is hashed.
+// Iteration ends at the end of the stream, or after yielding a read error.
+func Scan(hb hashbuffer.HashBuffer, hasher RollingHasher) iter.Seq2[Sum, error] {
+	return func(yield func(Sum, error) bool) {
+		window, err := hb.GetWindow()
+		if err != nil {
+			yield(Sum{}, err)
+			return
+		}
+		if len(window) == 0 {
+			return
+		}
+		hasher.Reset(window)
+		if !yield(Sum{hb.Offset(), hasher.Sum64()}, nil) {
+			return
+		}
+		for {
+			in, out, ok, err := hb.GetRoll()
+			if err != nil {
+				yield(Sum{}, err)
+				return
+			}
+			if !ok {
+				return
+			}
+			hasher.Roll(out, in)
+			if !yield(Sum{hb.Offset(), hasher.Sum64()}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// byteTable returns 256 pseudo-random values, generated from seed with splitmix64 so tables are
+// the same on every platform and in every run.
+func byteTable(seed uint64) (table [256]uint64) {
+	for i := range table {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return
+}
@@ -0,0 +1,150 @@
+package rolling
+
+import (
+	"fmt"
+	"hash/adler32"
+	"math/rand"
+	"os"
+	"testing"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+)
+
+// Make sure every hash, rolled across the whole file, matches hashing each window from scratch.
+func TestScan(t *testing.T) {
+	hashers := map[string]func() RollingHasher{
+		"RabinKarp": func() RollingHasher { return NewRabinKarp() },
+		"Rabin":     func() RollingHasher { return NewRabin() },
+		"Adler32":   func() RollingHasher { return NewAdler32() },
+		"Buzhash":   func() RollingHasher { return NewBuzhash() },
+		"Gear":      func() RollingHasher { return NewGear() },
+	}
+	for name, newHasher := range hashers {
+		for _, windowSize := range []int{1, 16, 64, 100} {
+			testScan(t, "../testdata/data_long", fmt.Sprintf("TestScan_%s_%d", name, windowSize), windowSize, newHasher)
+		}
+		testScan(t, "../testdata/data_0", fmt.Sprintf("TestScan_%s_data_0", name), 16, newHasher)
+		testScan(t, "../testdata/data_15", fmt.Sprintf("TestScan_%s_data_15", name), 16, newHasher)
+		testScan(t, "../testdata/data_1025", fmt.Sprintf("TestScan_%s_data_1025", name), 16, newHasher)
+	}
+}
+
+// Make sure Adler32 gives the same checksums as hash/adler32.
+func TestAdler32(t *testing.T) {
+	const windowSize = 32
+
+	data := readTestData(t, "../testdata/data_long")
+	hb, err := hashbuffer.NewBytesHashBuffer(data, windowSize)
+	check(t, err)
+	defer hb.Close()
+	for sum, err := range Scan(hb, NewAdler32()) {
+		check(t, err)
+		want := adler32.Checksum(data[sum.Offset : sum.Offset+windowSize])
+		if sum.Hash != uint64(want) {
+			t.Fatalf("Error TestAdler32: offset %d hash %#x, want %#x", sum.Offset, sum.Hash, want)
+		}
+	}
+}
+
+// Make sure Rabin gives the remainder of the window, as a polynomial over GF(2), divided by the polynomial.
+func TestRabin(t *testing.T) {
+	const windowSize = 48
+
+	data := readTestData(t, "../testdata/data_long")
+	hb, err := hashbuffer.NewBytesHashBuffer(data, windowSize)
+	check(t, err)
+	defer hb.Close()
+	for sum, err := range Scan(hb, NewRabin()) {
+		check(t, err)
+		var want uint64
+		for _, b := range data[sum.Offset : sum.Offset+windowSize] {
+			for bit := 7; bit >= 0; bit-- {
+				// multiply by x, add the next coefficient, and reduce once the degree reaches 53
+				want = want<<1 | uint64(b>>bit&1)
+				if want>>53 != 0 {
+					want ^= DefaultRabinPolynomial
+				}
+			}
+		}
+		if sum.Hash != want {
+			t.Fatalf("Error TestRabin: offset %d hash %#x, want %#x", sum.Offset, sum.Hash, want)
+		}
+		if sum.Offset == 1000 {
+			break
+		}
+	}
+}
+
+// Make sure Adler32 still matches hash/adler32 when the window size times a byte overflows 32 bits.
+func TestAdler32LargeWindow(t *testing.T) {
+	const windowSize = 17 << 20
+
+	data := make([]byte, windowSize+4)
+	rand.New(rand.NewSource(1)).Read(data)
+	hasher := NewAdler32()
+	hasher.Reset(data[:windowSize])
+	for i := 1; i+windowSize <= len(data); i++ {
+		hasher.Roll(data[i-1], data[i+windowSize-1])
+		if want := adler32.Checksum(data[i : i+windowSize]); hasher.Sum64() != uint64(want) {
+			t.Fatalf("Error TestAdler32LargeWindow: offset %d hash %#x, want %#x", i, hasher.Sum64(), want)
+		}
+	}
+}
+
+// Make sure breaking out of Scan() early stops it.
+func TestScanBreak(t *testing.T) {
+	hb, err := hashbuffer.NewFileHashBuffer("../testdata/data_long", 1024, 16)
+	check(t, err)
+	defer hb.Close()
+	count := 0
+	for _, err := range Scan(hb, NewBuzhash()) {
+		check(t, err)
+		count++
+		if count == 10 {
+			break
+		}
+	}
+	if count != 10 || hb.Offset() != 9 {
+		t.Errorf("Error TestScanBreak: count %d  offset %d", count, hb.Offset())
+	}
+}
+
+func testScan(t *testing.T, filename string, title string, windowSize int, newHasher func() RollingHasher) {
+	t.Logf("start %s", title)
+	data := readTestData(t, filename)
+	hb, err := hashbuffer.NewFileHashBuffer(filename, 1024, windowSize)
+	check(t, err)
+	defer hb.Close()
+	fresh := newHasher()
+	count := 0
+	for sum, err := range Scan(hb, newHasher()) {
+		check(t, err)
+		if sum.Offset != int64(count) {
+			t.Fatalf("Error %s: offset %d, want %d", title, sum.Offset, count)
+		}
+		end := min(count+windowSize, len(data))
+		fresh.Reset(data[count:end])
+		if sum.Hash != fresh.Sum64() {
+			t.Fatalf("Error %s: offset %d hash %#x, want %#x", title, sum.Offset, sum.Hash, fresh.Sum64())
+		}
+		count++
+	}
+	want := max(len(data)-windowSize+1, min(len(data), 1))
+	if count != want {
+		t.Errorf("Error %s: got %d sums, want %d", title, count, want)
+	}
+}
+
+func readTestData(t *testing.T, filename string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	check(t, err)
+	return data
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Error %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package signature
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// The binary encoding, all integers big-endian:
+//
+//	magic       4 bytes  "HBSG"
+//	version     1 byte   1
+//	block size  uint32
+//	strong size uint32
+//	length      uint64   total length of the blocks
+//	count       uint64   number of blocks
+//	blocks      count times: weak uint32, then strong size bytes of strong hash
+const (
+	magic   = "HBSG"
+	version = 1
+	// magic, version, block size, strong size, length, count
+	headerSize = 4 + 1 + 4 + 4 + 8 + 8
+)
+
+// ErrInvalidEncoding is returned when decoding data that is not a valid signature.
+var ErrInvalidEncoding = errors.New("signature: invalid encoding")
+
+// MarshalBinary encodes the signature so it can be stored or exchanged.
+func (signature *Signature) MarshalBinary() (data []byte, err error) {
+	var buffer bytes.Buffer
+	_, err = signature.WriteTo(&buffer)
+	data = buffer.Bytes()
+	return
+}
+
+// UnmarshalBinary decodes a signature encoded by MarshalBinary.
+func (signature *Signature) UnmarshalBinary(data []byte) (err error) {
+	reader := bytes.NewReader(data)
+	_, err = signature.ReadFrom(reader)
+	if err == nil && reader.Len() != 0 {
+		err = ErrInvalidEncoding
+	}
+	return
+}
+
+// WriteTo writes the encoded signature to writer.
+func (signature *Signature) WriteTo(writer io.Writer) (n int64, err error) {
+	if signature.BlockSize <= 0 || signature.StrongSize < 0 {
+		err = ErrInvalidEncoding
+		return
+	}
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = append(header, version)
+	header = binary.BigEndian.AppendUint32(header, uint32(signature.BlockSize))
+	header = binary.BigEndian.AppendUint32(header, uint32(signature.StrongSize))
+	header = binary.BigEndian.AppendUint64(header, uint64(signature.Length))
+	header = binary.BigEndian.AppendUint64(header, uint64(len(signature.Blocks)))
+	buffered := bufio.NewWriter(writer)
+	written, err := buffered.Write(header)
+	n += int64(written)
+	if err != nil {
+		return
+	}
+	entry := make([]byte, 4+signature.StrongSize)
+	for _, block := range signature.Blocks {
+		if len(block.Strong) != signature.StrongSize {
+			err = ErrInvalidEncoding
+			return
+		}
+		binary.BigEndian.PutUint32(entry, block.Weak)
+		copy(entry[4:], block.Strong)
+		written, err = buffered.Write(entry)
+		n += int64(written)
+		if err != nil {
+			return
+		}
+	}
+	err = buffered.Flush()
+	return
+}
+
+// ReadFrom reads an encoded signature from reader, replacing the contents of signature.
+// It reads exactly the bytes of the signature, so more data may follow it in the stream.
+func (signature *Signature) ReadFrom(reader io.Reader) (n int64, err error) {
+	header := make([]byte, headerSize)
+	read, err := io.ReadFull(reader, header)
+	n += int64(read)
+	if err != nil {
+		err = unexpectedEOF(err)
+		return
+	}
+	if string(header[:4]) != magic || header[4] != version {
+		err = ErrInvalidEncoding
+		return
+	}
+	blockSize := binary.BigEndian.Uint32(header[5:])
+	strongSize := binary.BigEndian.Uint32(header[9:])
+	length := binary.BigEndian.Uint64(header[13:])
+	count := binary.BigEndian.Uint64(header[21:])
+	// the blocks must account for exactly the length
+	if blockSize == 0 || strongSize > 1<<16 || length > 1<<62 ||
+		count != (length+uint64(blockSize)-1)/uint64(blockSize) {
+		err = ErrInvalidEncoding
+		return
+	}
+	decoded := Signature{BlockSize: int(blockSize), StrongSize: int(strongSize), Length: int64(length)}
+	entry := make([]byte, 4+strongSize)
+	for i := uint64(0); i < count; i++ {
+		read, err = io.ReadFull(reader, entry)
+		n += int64(read)
+		if err != nil {
+			err = unexpectedEOF(err)
+			return
+		}
+		decoded.Blocks = append(decoded.Blocks, Block{
+			Weak:   binary.BigEndian.Uint32(entry),
+			Strong: bytes.Clone(entry[4:]),
+		})
+	}
+	*signature = decoded
+	return
+}
+
+// unexpectedEOF reports a signature cut short as io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
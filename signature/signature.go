@@ -0,0 +1,68 @@
+// Package signature computes rsync-style block signatures of a stream read through a hashbuffer.HashBuffer.
+//
+// The stream is divided into fixed-size, non-overlapping blocks (the last one may be short), and each
+// block is described by a weak checksum, which can be rolled to check every offset of another stream
+// cheaply, and a strong hash to confirm a weak match.
+package signature
+
+import (
+	"errors"
+	"hash"
+	"hash/adler32"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+)
+
+// Block is the signature of one block.
+type Block struct {
+	// Adler-32 of the block; the same as rolling.Adler32 computes over a window of the block size
+	Weak uint32
+	// strong hash of the block
+	Strong []byte
+}
+
+// Signature is the list of block signatures of a stream.
+type Signature struct {
+	// size of each block; the last block may be shorter
+	BlockSize int
+	// length of each strong hash
+	StrongSize int
+	// total length of the blocks
+	Length int64
+	// one entry for each block, in stream order
+	Blocks []Block
+}
+
+// ErrInvalidBlockSize is returned when the block size is not positive, or the HashBuffer's window size
+// is not the block size.
+var ErrInvalidBlockSize = errors.New("signature: block size must be positive and equal to the window size")
+
+// GenerateSignature computes the signature of the remaining data in hb, which must have been created with
+// a window size of blockSize.  The blocks are read with hashbuffer.ReadBlocks().
+func GenerateSignature(hb hashbuffer.HashBuffer, blockSize int, strongHash func() hash.Hash) (signature *Signature, err error) {
+	if blockSize <= 0 {
+		err = ErrInvalidBlockSize
+		return
+	}
+	strong := strongHash()
+	signature = &Signature{BlockSize: blockSize, StrongSize: strong.Size()}
+	addBlock := func(block []byte) {
+		strong.Reset()
+		strong.Write(block)
+		signature.Blocks = append(signature.Blocks, Block{Weak: adler32.Checksum(block), Strong: strong.Sum(nil)})
+		signature.Length += int64(len(block))
+	}
+	ok, err := hashbuffer.ReadBlocks(hb, blockSize, addBlock)
+	if err == nil && !ok {
+		err = ErrInvalidBlockSize
+	}
+	return
+}
+
+// BlockLength returns the length of block index, which is BlockSize for all but possibly the last block.
+func (signature *Signature) BlockLength(index int) int {
+	if index == len(signature.Blocks)-1 {
+		return int(signature.Length - int64(index)*int64(signature.BlockSize))
+	}
+	return signature.BlockSize
+}
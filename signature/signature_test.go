@@ -0,0 +1,157 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"io"
+	"os"
+	"testing"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+)
+
+// Make sure each block's checksums match the block's data, for sizes around the block size.
+func TestGenerateSignature(t *testing.T) {
+	for _, filename := range []string{"data_0", "data_1", "data_15", "data_16", "data_17", "data_1023", "data_1024", "data_1025", "data_long"} {
+		for _, blockSize := range []int{1, 16, 1024, 1000} {
+			testGenerateSignature(t, "../testdata/"+filename, fmt.Sprintf("TestGenerateSignature_%s_%d", filename, blockSize), blockSize, md5.New)
+		}
+	}
+	testGenerateSignature(t, "../testdata/data_long", "TestGenerateSignature_sha256", 700, sha256.New)
+}
+
+// Make sure a block size that does not match the window size is rejected.
+func TestGenerateSignatureInvalid(t *testing.T) {
+	hb, err := hashbuffer.NewFileHashBuffer("../testdata/data_long", 1024, 16)
+	check(t, err)
+	defer hb.Close()
+	if _, err = GenerateSignature(hb, 0, md5.New); !errors.Is(err, ErrInvalidBlockSize) {
+		t.Errorf("Error TestGenerateSignatureInvalid: got %v for block size 0", err)
+	}
+	if _, err = GenerateSignature(hb, 8, md5.New); !errors.Is(err, ErrInvalidBlockSize) {
+		t.Errorf("Error TestGenerateSignatureInvalid: got %v for block size 8 with window size 16", err)
+	}
+	short, err := hashbuffer.NewFileHashBuffer("../testdata/data_1023", 1024, 8)
+	check(t, err)
+	defer short.Close()
+	if _, err = GenerateSignature(short, 16, md5.New); !errors.Is(err, ErrInvalidBlockSize) {
+		t.Errorf("Error TestGenerateSignatureInvalid: got %v for block size 16 with window size 8", err)
+	}
+}
+
+// Make sure a signature survives encoding and decoding, and is read exactly, leaving following data.
+func TestSignatureEncoding(t *testing.T) {
+	for _, filename := range []string{"data_0", "data_17", "data_long"} {
+		title := "TestSignatureEncoding_" + filename
+		signature := generate(t, "../testdata/"+filename, 1000, md5.New)
+		data, err := signature.MarshalBinary()
+		check(t, err)
+		var decoded Signature
+		check(t, decoded.UnmarshalBinary(data))
+		compareSignatures(t, title, &decoded, signature)
+
+		reader := bytes.NewReader(append(data, "trailing"...))
+		var read Signature
+		n, err := read.ReadFrom(reader)
+		check(t, err)
+		if n != int64(len(data)) || reader.Len() != len("trailing") {
+			t.Errorf("Error %s: read %d bytes, want %d", title, n, len(data))
+		}
+		compareSignatures(t, title, &read, signature)
+	}
+}
+
+// Make sure corrupt or truncated encodings are rejected.
+func TestSignatureEncodingInvalid(t *testing.T) {
+	data, err := generate(t, "../testdata/data_long", 1000, md5.New).MarshalBinary()
+	check(t, err)
+	var signature Signature
+	if err = signature.UnmarshalBinary(data[:len(data)-1]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error TestSignatureEncodingInvalid: got %v for truncated data", err)
+	}
+	if err = signature.UnmarshalBinary(data[:3]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error TestSignatureEncodingInvalid: got %v for truncated header", err)
+	}
+	corrupt := bytes.Clone(data)
+	corrupt[0] = 'X'
+	if err = signature.UnmarshalBinary(corrupt); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("Error TestSignatureEncodingInvalid: got %v for bad magic", err)
+	}
+	corrupt = bytes.Clone(data)
+	corrupt[headerSize-1] ^= 1 // low byte of the block count
+	if err = signature.UnmarshalBinary(corrupt); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("Error TestSignatureEncodingInvalid: got %v for a bad block count", err)
+	}
+	if err = signature.UnmarshalBinary(append(bytes.Clone(data), 0)); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("Error TestSignatureEncodingInvalid: got %v for trailing data", err)
+	}
+}
+
+func testGenerateSignature(t *testing.T, filename string, title string, blockSize int, strongHash func() hash.Hash) {
+	t.Logf("start %s", title)
+	data := readTestData(t, filename)
+	signature := generate(t, filename, blockSize, strongHash)
+	if signature.BlockSize != blockSize || signature.Length != int64(len(data)) {
+		t.Errorf("Error %s: block size %d length %d", title, signature.BlockSize, signature.Length)
+	}
+	if want := (len(data) + blockSize - 1) / blockSize; len(signature.Blocks) != want {
+		t.Fatalf("Error %s: got %d blocks, want %d", title, len(signature.Blocks), want)
+	}
+	strong := strongHash()
+	for i, block := range signature.Blocks {
+		start := i * blockSize
+		end := start + signature.BlockLength(i)
+		if end != min(start+blockSize, len(data)) {
+			t.Fatalf("Error %s: block %d has length %d", title, i, signature.BlockLength(i))
+		}
+		strong.Reset()
+		strong.Write(data[start:end])
+		if block.Weak != adler32.Checksum(data[start:end]) || !bytes.Equal(block.Strong, strong.Sum(nil)) {
+			t.Fatalf("Error %s: block %d has the wrong checksums", title, i)
+		}
+	}
+}
+
+func compareSignatures(t *testing.T, title string, got *Signature, want *Signature) {
+	t.Helper()
+	if got.BlockSize != want.BlockSize || got.StrongSize != want.StrongSize ||
+		got.Length != want.Length || len(got.Blocks) != len(want.Blocks) {
+		t.Fatalf("Error %s: got %d/%d/%d/%d, want %d/%d/%d/%d", title,
+			got.BlockSize, got.StrongSize, got.Length, len(got.Blocks),
+			want.BlockSize, want.StrongSize, want.Length, len(want.Blocks))
+	}
+	for i := range got.Blocks {
+		if got.Blocks[i].Weak != want.Blocks[i].Weak || !bytes.Equal(got.Blocks[i].Strong, want.Blocks[i].Strong) {
+			t.Fatalf("Error %s: block %d differs", title, i)
+		}
+	}
+}
+
+func generate(t *testing.T, filename string, blockSize int, strongHash func() hash.Hash) *Signature {
+	t.Helper()
+	hb, err := hashbuffer.NewFileHashBuffer(filename, 4096, blockSize)
+	check(t, err)
+	defer hb.Close()
+	signature, err := GenerateSignature(hb, blockSize, strongHash)
+	check(t, err)
+	return signature
+}
+
+func readTestData(t *testing.T, filename string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	check(t, err)
+	return data
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Error %v", err)
+	}
+}
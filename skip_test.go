@@ -0,0 +1,152 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+)
+
+// Make sure Skip() over a seekable source gives the same results as reading through, while reading less.
+func TestSkipSeek(t *testing.T) {
+	for _, count := range []int{0, 1, 1008, 1009, 2000, 30000, 35522, 35523, 35524, 1 << 40} {
+		testSkipSeek(t, fmt.Sprintf("TestSkipSeek_%d", count), count)
+	}
+}
+
+// Make sure a huge Skip() through a non-seekable source with a small buffer neither recurses nor loses data.
+func TestSkipIterative(t *testing.T) {
+	const title = "TestSkipIterative"
+
+	hb, err := NewReaderHashBuffer(io.MultiReader(bytes.NewReader(testData)), 17, 16)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	skipped, err := hb.Skip(1 << 40)
+	check(t, err)
+	if skipped != len(testData)-16 {
+		t.Errorf("Error %s: skipped %d, should have skipped %d", title, skipped, len(testData)-16)
+	}
+	testGet(t, hb, title, testData, len(testData)-16)
+	testGetZero(t, hb, title)
+}
+
+// Make sure Skip() through a reader returning short reads keeps reading until it has skipped all it was
+// asked to, and stops only at the last full window of the stream.
+func TestSkipShortReads(t *testing.T) {
+	for _, count := range []int{1, 17, 1000, 5000, len(testData) - 17, len(testData) - 16, 1 << 40} {
+		for _, windowSize := range []int{1, 7, 16} {
+			title := fmt.Sprintf("TestSkipShortReads_%d_%d", count, windowSize)
+			hb, err := NewReaderHashBuffer(iotest.HalfReader(bytes.NewReader(testData)), 64, windowSize)
+			check(t, err)
+			testGet(t, hb, title, testData, 0)
+			skipped, err := hb.Skip(count)
+			check(t, err)
+			// it stops with the last full window next
+			if want := min(count, len(testData)-windowSize-1); skipped != want {
+				t.Errorf("Error %s: skipped %d, should have skipped %d", title, skipped, want)
+			}
+			testGet(t, hb, title, testData, skipped+1)
+			closeTestHashBuffer(t, hb)
+		}
+	}
+}
+
+// Make sure GetRoll() still reports the outgoing byte after a seek.
+func TestSkipSeekGetRoll(t *testing.T) {
+	const title = "TestSkipSeekGetRoll"
+
+	hb, err := NewFileHashBuffer("./testdata/data_long", 1024, 16)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	testGet(t, hb, title, testData, 0)
+	skipped, err := hb.Skip(20000)
+	check(t, err)
+	if skipped != 20000 {
+		t.Fatalf("Error %s: skipped %d, should have skipped 20000", title, skipped)
+	}
+	in, out, ok, err := hb.GetRoll()
+	check(t, err)
+	if !ok || in != testData[20001+15] || out != testData[20000] {
+		t.Errorf("Error %s: got in %#x out %#x ok %t, want in %#x out %#x",
+			title, in, out, ok, testData[20001+15], testData[20000])
+	}
+	testOffset(t, hb, title, 20001)
+}
+
+func testSkipSeek(t *testing.T, title string, count int) {
+	const bufferSize = 1024
+	const windowSize = 16
+
+	t.Logf("start %s", title)
+	source := newTestCountingSeeker(bytes.NewReader(testData))
+	hb, err := NewReaderHashBuffer(source, bufferSize, windowSize)
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	// io.MultiReader hides the io.Seeker, so this one has to read through
+	rhb, err := NewReaderHashBuffer(io.MultiReader(bytes.NewReader(testData)), bufferSize, windowSize)
+	check(t, err)
+	defer closeTestHashBuffer(t, rhb)
+
+	testGet(t, hb, title, testData, 0)
+	testGet(t, rhb, title, testData, 0)
+	skipped, err := hb.Skip(count)
+	check(t, err)
+	want, err := rhb.Skip(count)
+	check(t, err)
+	if skipped != want {
+		t.Errorf("Error %s: skipped %d, should have skipped %d", title, skipped, want)
+	}
+	if hb.Offset() != rhb.Offset() {
+		t.Errorf("Error %s: Offset() %d, want %d", title, hb.Offset(), rhb.Offset())
+	}
+	for i := 0; ; i++ {
+		window, err := hb.GetWindow()
+		check(t, err)
+		wantWindow, err := rhb.GetWindow()
+		check(t, err)
+		if !testEq(window, wantWindow) {
+			t.Fatalf("Error %s: window %d is %#x, want %#x", title, i, window, wantWindow)
+		}
+		if len(wantWindow) == 0 {
+			break
+		}
+	}
+	if count >= 2*bufferSize && source.read.Load() >= int64(len(testData)) {
+		t.Errorf("Error %s: read %d bytes, expected seeking to read less", title, source.read.Load())
+	}
+}
+
+// testCountingReader counts the bytes read through it and the times it is closed, and can be used from
+// several goroutines.
+type testCountingReader struct {
+	io.Reader
+	read   atomic.Int64
+	closed atomic.Int32
+}
+
+func (r *testCountingReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	r.read.Add(int64(n))
+	return
+}
+
+func (r *testCountingReader) Close() error {
+	r.closed.Add(1)
+	return nil
+}
+
+// testCountingSeeker is a testCountingReader over a seekable reader, which is an io.Seeker itself.
+type testCountingSeeker struct {
+	testCountingReader
+	seeker io.Seeker
+}
+
+func newTestCountingSeeker(reader io.ReadSeeker) *testCountingSeeker {
+	return &testCountingSeeker{testCountingReader: testCountingReader{Reader: reader}, seeker: reader}
+}
+
+func (r *testCountingSeeker) Seek(offset int64, whence int) (int64, error) {
+	return r.seeker.Seek(offset, whence)
+}
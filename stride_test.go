@@ -0,0 +1,97 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// Make sure GetWindowStride() returns the windows a stride apart, across refills and with either buffer strategy.
+func TestGetWindowStride(t *testing.T) {
+	for _, size := range []int{1023, 1024, 1025, len(testData)} {
+		for _, stride := range []int{0, 1, 4, 16, 63, 100, 5000} {
+			for _, strategy := range []BufferStrategy{CompactBuffer, RingBuffer} {
+				title := fmt.Sprintf("TestGetWindowStride_%d_%d_%d", size, stride, strategy)
+				// a reader that is not an io.Seeker, so the larger strides read through the data
+				hb, err := NewReader(onlyReader{bytes.NewReader(testData[:size])}, WithBufferSize(64),
+					WithWindowSize(16), WithBufferStrategy(strategy))
+				check(t, err)
+				count := 0
+				for offset := 0; offset+16 <= size; offset += max(stride, 1) {
+					window, err := hb.GetWindowStride(stride)
+					check(t, err)
+					if !testEq(window, testData[offset:offset+16]) {
+						t.Fatalf("Error %s: window at offset %d does not match", title, offset)
+					}
+					count++
+				}
+				if window, err := hb.GetWindowStride(stride); err != nil || len(window) != 0 {
+					t.Errorf("Error %s: got a window of %d bytes, %v after %d windows", title, len(window), err, count)
+				}
+				closeTestHashBuffer(t, hb)
+			}
+		}
+	}
+}
+
+// Make sure a stream shorter than the window gives one short window, whatever the stride.
+func TestGetWindowStrideShort(t *testing.T) {
+	for _, size := range []int{0, 1, 15} {
+		title := fmt.Sprintf("TestGetWindowStrideShort_%d", size)
+		hb, err := NewReaderHashBuffer(bytes.NewReader(testData[:size]), 64, 16)
+		check(t, err)
+		window, err := hb.GetWindowStride(16)
+		check(t, err)
+		if !testEq(window, testData[:size]) && size > 0 {
+			t.Errorf("Error %s: got %d bytes, want %d", title, len(window), size)
+		}
+		testGetZero(t, hb, title)
+		closeTestHashBuffer(t, hb)
+	}
+}
+
+// Make sure GetWindowStride() can change the step from one call to the next, and from GetWindow().
+func TestGetWindowStrideMixed(t *testing.T) {
+	const title = "TestGetWindowStrideMixed"
+
+	hb, err := NewReader(bytes.NewReader(testData), WithBufferSize(64), WithWindowSize(16), WithStride(16))
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	testGet(t, hb, title, testData, 0)
+	offset := 0
+	for _, stride := range []int{1, 3, 60, 2, 200, 16} {
+		offset += stride
+		window, err := hb.GetWindowStride(stride)
+		check(t, err)
+		if !testEq(window, testData[offset:offset+16]) {
+			t.Errorf("Error %s: window at offset %d does not match", title, offset)
+		}
+	}
+	testGet(t, hb, title, testData, offset+16)
+}
+
+// Compare GetWindowStride() with GetWindow() followed by Skip(), for non-overlapping blocks.
+func BenchmarkGetWindowStride(b *testing.B) {
+	data := bytes.Repeat(testData, 64)
+	for _, windowSize := range []int{16, 64} {
+		b.Run(fmt.Sprintf("GetWindowStride/%d", windowSize), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for range b.N {
+				hb, _ := NewReader(onlyReader{bytes.NewReader(data)}, WithWindowSize(windowSize))
+				for window, _ := hb.GetWindowStride(windowSize); len(window) > 0; window, _ = hb.GetWindowStride(windowSize) {
+				}
+				hb.Close()
+			}
+		})
+		b.Run(fmt.Sprintf("Skip/%d", windowSize), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for range b.N {
+				hb, _ := NewReader(onlyReader{bytes.NewReader(data)}, WithWindowSize(windowSize))
+				for window, _ := hb.GetWindow(); len(window) > 0; window, _ = hb.GetWindow() {
+					hb.Skip(windowSize - 1)
+				}
+				hb.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package hashbuffer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrTeeStarted is returned by Tee.NewHashBuffer once the Tee has started reading the stream.
+var ErrTeeStarted = errors.New("hashbuffer: tee has already started reading")
+
+// Tee reads a stream once for several HashBuffers, each with its own options (window size, stride, etc.).
+// Create every HashBuffer with NewHashBuffer before any is used, then use each on its own goroutine: the
+// stream is read only as far as bufferSize bytes ahead of the slowest HashBuffer, so one that runs ahead
+// waits for the others.  Closing a HashBuffer removes it, so it no longer holds the others back.
+// A read error is returned by each HashBuffer, after the data before it.  The stream, if it is an
+// io.Closer, is closed once it has been read to the end or every HashBuffer has been closed.
+type Tee struct {
+	mu sync.Mutex
+	// signalled when data is added, a consumer moves forward or is closed, or a read ends
+	cond   *sync.Cond
+	reader io.Reader
+	// the amount read at a time, and the most that is kept ahead of the slowest consumer
+	bufferSize int
+	// data read but not yet consumed by every consumer; start is the stream offset of data[0]
+	data  []byte
+	start int64
+	// the target of the read in progress, which is done without holding mu
+	chunk   []byte
+	reading bool
+	// set once a consumer has read; no more can be added
+	started bool
+	// the error that ended the stream (io.EOF at the end)
+	err       error
+	consumers map[*teeReader]struct{}
+	released  bool
+}
+
+// teeReader is the io.Reader of one of the HashBuffers of a Tee.
+type teeReader struct {
+	tee *Tee
+	// stream offset of the next byte to be read
+	pos    int64
+	closed bool
+}
+
+// NewTee creates a Tee reading the specified io.Reader, bufferSize bytes at a time (DefaultBufferSize if 0).
+func NewTee(reader io.Reader, bufferSize int) (tee *Tee, err error) {
+	if bufferSize < 0 {
+		err = fmt.Errorf("%w: got %d", ErrInvalidBufferSize, bufferSize)
+		return
+	}
+	if bufferSize == 0 {
+		bufferSize = DefaultBufferSize
+	}
+	tee = &Tee{
+		reader:     reader,
+		bufferSize: bufferSize,
+		data:       make([]byte, 0, 2*bufferSize),
+		chunk:      make([]byte, bufferSize),
+		consumers:  make(map[*teeReader]struct{}),
+	}
+	tee.cond = sync.NewCond(&tee.mu)
+	return
+}
+
+// NewHashBuffer creates a HashBuffer over the stream of the Tee, configured by options as NewReader is;
+// WithWindowSize is required.  WithReadAhead does not apply, since its goroutine would start reading the
+// stream before every HashBuffer has been created.  It returns ErrTeeStarted once any HashBuffer of the Tee
+// has been used.
+func (tee *Tee) NewHashBuffer(options ...Option) (hashBuffer HashBuffer, err error) {
+	o, err := newOptions(options, false)
+	if err != nil {
+		return
+	}
+	if o.readAhead {
+		err = fmt.Errorf("%w: read-ahead of a tee", ErrInvalidOption)
+		return
+	}
+	tee.mu.Lock()
+	defer tee.mu.Unlock()
+	if tee.started {
+		err = ErrTeeStarted
+		return
+	}
+	r := &teeReader{tee: tee}
+	hashBuffer, err = NewReader(r, options...)
+	if err != nil {
+		return
+	}
+	tee.consumers[r] = struct{}{}
+	return
+}
+
+// Read copies the data after the consumer's position, reading more of the stream when it has caught up,
+// unless that would take the stream more than bufferSize ahead of the slowest consumer.
+func (r *teeReader) Read(p []byte) (n int, err error) {
+	tee := r.tee
+	tee.mu.Lock()
+	defer tee.mu.Unlock()
+	tee.started = true
+	for {
+		if r.closed {
+			return 0, ErrClosed
+		}
+		if available := tee.start + int64(len(tee.data)) - r.pos; available > 0 {
+			n = copy(p, tee.data[r.pos-tee.start:])
+			r.pos += int64(n)
+			// the consumer may have been the slowest, holding back the one reading
+			tee.cond.Broadcast()
+			return
+		}
+		if tee.err != nil {
+			return 0, tee.err
+		}
+		if tee.reading || tee.start+int64(len(tee.data))-tee.slowest() >= int64(tee.bufferSize) {
+			tee.cond.Wait()
+			continue
+		}
+		tee.fill()
+	}
+}
+
+// fill reads the next chunk of the stream, releasing mu while it waits, and adds it to the data.
+func (tee *Tee) fill() {
+	tee.reading = true
+	tee.mu.Unlock()
+	n, err := tee.reader.Read(tee.chunk)
+	tee.mu.Lock()
+	tee.reading = false
+	// drop the data every consumer has read, to make room
+	slowest := tee.slowest()
+	consumed := int(slowest - tee.start)
+	tee.data = tee.data[:copy(tee.data, tee.data[consumed:])]
+	tee.start = slowest
+	tee.data = append(tee.data, tee.chunk[:n]...)
+	if err != nil {
+		tee.err = err
+	}
+	// the stream is not needed at the end, or if the last consumer was closed during the read
+	if err != nil || len(tee.consumers) == 0 {
+		tee.release()
+	}
+	tee.cond.Broadcast()
+}
+
+// slowest returns the position of the consumer furthest behind, or the end of the data if there is none.
+func (tee *Tee) slowest() (pos int64) {
+	pos = tee.start + int64(len(tee.data))
+	for r := range tee.consumers {
+		pos = min(pos, r.pos)
+	}
+	return
+}
+
+// release closes the stream, if it is an io.Closer, the first time it is called.
+func (tee *Tee) release() (err error) {
+	if tee.released {
+		return
+	}
+	tee.released = true
+	if closer, ok := tee.reader.(io.Closer); ok {
+		err = closer.Close()
+	}
+	return
+}
+
+// Close removes the consumer from the Tee, waking a read waiting for it.  Closing the last one closes the
+// stream, and returns its error.
+func (r *teeReader) Close() (err error) {
+	tee := r.tee
+	tee.mu.Lock()
+	defer tee.mu.Unlock()
+	if r.closed {
+		return
+	}
+	r.closed = true
+	delete(tee.consumers, r)
+	// a read in progress is left to finish, and fill closes the stream after it
+	if len(tee.consumers) == 0 && !tee.reading {
+		err = tee.release()
+	}
+	tee.cond.Broadcast()
+	return
+}
@@ -0,0 +1,170 @@
+package hashbuffer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// testTeeHash hashes every window of hb; it is run on a goroutine, so it returns errors rather than fail the test.
+func testTeeHash(hb HashBuffer) (hashes []uint32, err error) {
+	for window, err := range hb.Windows() {
+		if err != nil {
+			return hashes, err
+		}
+		hashes = append(hashes, crc32.ChecksumIEEE(window))
+	}
+	return
+}
+
+// Make sure each HashBuffer of a Tee, with its own options, returns the same windows as on its own, while
+// the stream is read just once.
+func TestTee(t *testing.T) {
+	optionSets := [][]Option{
+		{WithWindowSize(16)},
+		{WithWindowSize(1024), WithStride(1024)},
+		{WithWindowSize(4), WithBufferSize(16), WithBufferStrategy(RingBuffer)},
+		{WithWindowSize(64), WithBufferSize(100), WithContext(context.Background())},
+	}
+	for _, size := range []int{0, 10, 1025, len(testData)} {
+		title := fmt.Sprintf("TestTee_%d", size)
+		source := &testCountingReader{Reader: bytes.NewReader(testData[:size])}
+		tee, err := NewTee(source, 100)
+		check(t, err)
+		hbs := make([]HashBuffer, len(optionSets))
+		for i, options := range optionSets {
+			hbs[i], err = tee.NewHashBuffer(options...)
+			check(t, err)
+		}
+		results := make([][]uint32, len(hbs))
+		errs := make([]error, len(hbs))
+		var wg sync.WaitGroup
+		for i, hb := range hbs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], errs[i] = testTeeHash(hb)
+			}()
+		}
+		wg.Wait()
+		for i, options := range optionSets {
+			check(t, errs[i])
+			alone, err := NewReader(bytes.NewReader(testData[:size]), options...)
+			check(t, err)
+			want, err := testTeeHash(alone)
+			check(t, err)
+			if !slices.Equal(results[i], want) {
+				t.Errorf("Error %s: HashBuffer %d got %d windows, which differ from the %d on its own", title, i, len(results[i]), len(want))
+			}
+			closeTestHashBuffer(t, hbs[i])
+			closeTestHashBuffer(t, alone)
+		}
+		if read := source.read.Load(); read != int64(size) {
+			t.Errorf("Error %s: read %d bytes of %d", title, read, size)
+		}
+		if closed := source.closed.Load(); closed != 1 {
+			t.Errorf("Error %s: stream closed %d times", title, closed)
+		}
+	}
+}
+
+// Make sure a HashBuffer that runs ahead waits for the slowest, until that one is closed.
+func TestTeeBackpressure(t *testing.T) {
+	const title = "TestTeeBackpressure"
+
+	source := &testCountingReader{Reader: bytes.NewReader(testData)}
+	tee, err := NewTee(source, 100)
+	check(t, err)
+	fast, err := tee.NewHashBuffer(WithWindowSize(16), WithBufferSize(64))
+	check(t, err)
+	slow, err := tee.NewHashBuffer(WithWindowSize(16), WithBufferSize(64))
+	check(t, err)
+	done := make(chan error)
+	go func() {
+		_, err := testTeeHash(fast)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatalf("Error %s: the fast HashBuffer was not held back", title)
+	default:
+	}
+	// the stream is at most a buffer ahead of the slow one, which has read nothing, plus the read that got it there
+	if read := source.read.Load(); read > 2*100 {
+		t.Errorf("Error %s: read %d bytes ahead of the slowest", title, read)
+	}
+	check(t, slow.Close())
+	select {
+	case err = <-done:
+		check(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Error %s: the fast HashBuffer still waits after the slow one was closed", title)
+	}
+	if read := source.read.Load(); read != int64(len(testData)) {
+		t.Errorf("Error %s: read %d bytes of %d", title, read, len(testData))
+	}
+	closeTestHashBuffer(t, fast)
+}
+
+// Make sure a read error reaches every HashBuffer, at the offset it happened at.
+func TestTeeError(t *testing.T) {
+	const title = "TestTeeError"
+
+	tee, err := NewTee(io.MultiReader(bytes.NewReader(testData[:1000]), iotest.ErrReader(errTestRead)), 100)
+	check(t, err)
+	hbs := make([]HashBuffer, 3)
+	for i := range hbs {
+		hbs[i], err = tee.NewHashBuffer(WithWindowSize(16 << i))
+		check(t, err)
+	}
+	errs := make([]error, len(hbs))
+	var wg sync.WaitGroup
+	for i, hb := range hbs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = testTeeHash(hb)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		var readError *ReadError
+		if !errors.As(err, &readError) || !errors.Is(err, errTestRead) || readError.Offset != 1000 {
+			t.Errorf("Error %s: HashBuffer %d got %v, want a *ReadError at offset 1000", title, i, err)
+		}
+		hbs[i].Close()
+	}
+}
+
+// Make sure HashBuffers cannot be added once the Tee has started reading, and invalid options are rejected.
+func TestTeeStarted(t *testing.T) {
+	const title = "TestTeeStarted"
+
+	tee, err := NewTee(bytes.NewReader(testData), 0)
+	check(t, err)
+	if _, err = tee.NewHashBuffer(); !errors.Is(err, ErrInvalidWindowSize) {
+		t.Errorf("Error %s: got %v without a window size, want ErrInvalidWindowSize", title, err)
+	}
+	if _, err = tee.NewHashBuffer(WithWindowSize(16), WithReadAhead(true)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Error %s: got %v with read-ahead, want ErrInvalidOption", title, err)
+	}
+	hb, err := tee.NewHashBuffer(WithWindowSize(16))
+	check(t, err)
+	defer closeTestHashBuffer(t, hb)
+	testGet(t, hb, title, testData, 0)
+	if _, err = tee.NewHashBuffer(WithWindowSize(16)); !errors.Is(err, ErrTeeStarted) {
+		t.Errorf("Error %s: got %v, want ErrTeeStarted", title, err)
+	}
+	if _, err = NewTee(bytes.NewReader(testData), -1); !errors.Is(err, ErrInvalidBufferSize) {
+		t.Errorf("Error %s: got %v for a negative buffer size, want ErrInvalidBufferSize", title, err)
+	}
+}
@@ -0,0 +1,354 @@
+package vcdiff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash/adler32"
+	"io"
+)
+
+// maxInteger bounds the integers of a delta, so sizes and offsets always fit in an int64.
+const maxInteger = 1 << 62
+
+// TargetHistorySize is how much of the target Decode keeps in memory for VCD_TARGET windows to copy from.
+const TargetHistorySize = 64 << 20
+
+// ReadWriterAt is a target that DecodeTo writes at offsets from 0 and reads back from.
+type ReadWriterAt interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// Decode applies the VCDIFF delta read from delta to source, writing the rebuilt target to target.
+// source may be nil if the delta does not refer to it.  Windows may copy from earlier target data
+// (VCD_TARGET), which is kept in memory, but only the last TargetHistorySize bytes of it; a delta copying
+// from further back returns ErrTargetHistory, and can be applied with DecodeTo instead.
+func Decode(source io.ReaderAt, delta io.Reader, target io.Writer) (err error) {
+	d := newDecoder(source, delta)
+	d.target = target
+	d.historySize = TargetHistorySize
+	return d.decode()
+}
+
+// DecodeTo applies the VCDIFF delta read from delta to source, as Decode does, writing the rebuilt target
+// to target from offset 0.  Earlier target data that windows copy from (VCD_TARGET) is read back from
+// target, so none of it is kept in memory; target must be readable, such as an *os.File opened for reading
+// and writing without O_APPEND.
+func DecodeTo(source io.ReaderAt, delta io.Reader, target ReadWriterAt) (err error) {
+	d := newDecoder(source, delta)
+	d.targetAt = target
+	return d.decode()
+}
+
+// decoder holds the state of one Decode or DecodeTo.
+type decoder struct {
+	source io.ReaderAt
+	reader *bufio.Reader
+	// the target of Decode, or of DecodeTo
+	target   io.Writer
+	targetAt ReadWriterAt
+	// the end of the target written so far, from historyStart, kept by Decode; it grows to at most
+	// twice historySize before the start is dropped
+	history      []byte
+	historyStart int64
+	historySize  int
+	// number of target bytes written so far
+	written int64
+}
+
+func newDecoder(source io.ReaderAt, delta io.Reader) *decoder {
+	return &decoder{source: source, reader: bufio.NewReader(delta)}
+}
+
+func (d *decoder) decode() (err error) {
+	if err = d.readHeader(); err != nil {
+		return
+	}
+	for {
+		var more bool
+		more, err = d.readWindow()
+		if err != nil || !more {
+			return
+		}
+	}
+}
+
+func (d *decoder) readHeader() (err error) {
+	header := make([]byte, len(magic)+1)
+	if _, err = io.ReadFull(d.reader, header); err != nil {
+		return unexpectedEOF(err)
+	}
+	if !bytes.Equal(header[:len(magic)], magic) {
+		return ErrInvalidDelta
+	}
+	indicator := header[len(magic)]
+	if indicator&(vcdDecompress|vcdCodeTable) != 0 {
+		return ErrUnsupported
+	}
+	if indicator&^vcdAppHeader != 0 {
+		return ErrInvalidDelta
+	}
+	if indicator&vcdAppHeader != 0 {
+		var length int64
+		if length, err = readInteger(d.reader); err != nil {
+			return
+		}
+		if _, err = io.CopyN(io.Discard, d.reader, length); err != nil {
+			return unexpectedEOF(err)
+		}
+	}
+	return
+}
+
+// readWindow decodes one window and writes its target data; more is false at the end of the delta.
+func (d *decoder) readWindow() (more bool, err error) {
+	indicator, err := d.reader.ReadByte()
+	if err == io.EOF {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	if indicator&^(vcdSource|vcdTarget|vcdAdler32) != 0 || indicator&(vcdSource|vcdTarget) == vcdSource|vcdTarget {
+		err = ErrInvalidDelta
+		return
+	}
+	var segment []byte
+	if indicator&(vcdSource|vcdTarget) != 0 {
+		if segment, err = d.readSegment(indicator&vcdTarget != 0); err != nil {
+			return
+		}
+	}
+	length, err := readInteger(d.reader)
+	if err != nil {
+		return
+	}
+	// read through a limited reader so a corrupt length cannot allocate more than is there
+	var body bytes.Buffer
+	if _, err = io.Copy(&body, io.LimitReader(d.reader, length)); err != nil {
+		return
+	}
+	if int64(body.Len()) != length {
+		err = io.ErrUnexpectedEOF
+		return
+	}
+	window, err := decodeWindow(segment, body.Bytes(), indicator&vcdAdler32 != 0)
+	if err != nil {
+		return
+	}
+	if err = d.write(window); err != nil {
+		return
+	}
+	more = true
+	return
+}
+
+// write writes a window of the target, keeping the end of the target in the history for Decode.
+func (d *decoder) write(window []byte) (err error) {
+	if d.targetAt != nil {
+		_, err = d.targetAt.WriteAt(window, d.written)
+		d.written += int64(len(window))
+		return
+	}
+	if _, err = d.target.Write(window); err != nil {
+		return
+	}
+	d.written += int64(len(window))
+	d.history = append(d.history, window...)
+	if drop := len(d.history) - d.historySize; len(d.history) > 2*d.historySize {
+		d.history = d.history[:copy(d.history, d.history[drop:])]
+		d.historyStart += int64(drop)
+	}
+	return
+}
+
+// readSegment reads the source segment position and size, and the segment itself from the source
+// or from the target written so far.
+func (d *decoder) readSegment(fromTarget bool) (segment []byte, err error) {
+	length, err := readInteger(d.reader)
+	if err != nil {
+		return
+	}
+	position, err := readInteger(d.reader)
+	if err != nil {
+		return
+	}
+	if fromTarget {
+		if position+length > d.written {
+			err = ErrInvalidDelta
+			return
+		}
+		if d.targetAt != nil {
+			return readSegmentAt(d.targetAt, position, length)
+		}
+		if position < d.historyStart {
+			err = ErrTargetHistory
+			return
+		}
+		segment = d.history[position-d.historyStart : position-d.historyStart+length]
+		return
+	}
+	if d.source == nil {
+		err = ErrInvalidDelta
+		return
+	}
+	return readSegmentAt(d.source, position, length)
+}
+
+func readSegmentAt(reader io.ReaderAt, position int64, length int64) (segment []byte, err error) {
+	var buffer bytes.Buffer
+	copied, err := io.Copy(&buffer, io.NewSectionReader(reader, position, length))
+	if err == nil && copied != length {
+		err = ErrInvalidDelta
+	}
+	segment = buffer.Bytes()
+	return
+}
+
+// decodeWindow runs the instructions in the delta encoding of a window, returning the target window.
+func decodeWindow(segment []byte, body []byte, hasChecksum bool) (window []byte, err error) {
+	reader := bytes.NewReader(body)
+	targetLength, err := readInteger(reader)
+	if err != nil {
+		err = ErrInvalidDelta
+		return
+	}
+	// Delta_Indicator: compression of the sections
+	indicator, err := reader.ReadByte()
+	if err != nil {
+		err = ErrInvalidDelta
+		return
+	}
+	if indicator != 0 {
+		err = ErrUnsupported
+		return
+	}
+	var lengths [3]int64
+	for i := range lengths {
+		if lengths[i], err = readInteger(reader); err != nil {
+			err = ErrInvalidDelta
+			return
+		}
+	}
+	dataLength, instLength, addrLength := lengths[0], lengths[1], lengths[2]
+	var checksum [4]byte
+	if hasChecksum {
+		if _, err = io.ReadFull(reader, checksum[:]); err != nil {
+			err = ErrInvalidDelta
+			return
+		}
+	}
+	if int64(reader.Len()) != dataLength+instLength+addrLength {
+		err = ErrInvalidDelta
+		return
+	}
+	sections := body[len(body)-reader.Len():]
+	data := sections[:dataLength]
+	inst := bytes.NewReader(sections[dataLength : dataLength+instLength])
+	addr := bytes.NewReader(sections[dataLength+instLength:])
+
+	var cache addressCache
+	segmentLength := int64(len(segment))
+	// the target length is only trusted as far as the instructions bear it out
+	window = make([]byte, 0, min(targetLength, 1<<20))
+	for inst.Len() > 0 {
+		index, _ := inst.ReadByte()
+		for _, in := range defaultCodeTable[index] {
+			if in.kind == instNoop {
+				continue
+			}
+			size := int64(in.size)
+			if size == 0 {
+				if size, err = readInteger(inst); err != nil {
+					err = ErrInvalidDelta
+					return
+				}
+			}
+			if int64(len(window))+size > targetLength {
+				err = ErrInvalidDelta
+				return
+			}
+			switch in.kind {
+			case instAdd:
+				if int64(len(data)) < size {
+					err = ErrInvalidDelta
+					return
+				}
+				window = append(window, data[:size]...)
+				data = data[size:]
+			case instRun:
+				if len(data) == 0 {
+					err = ErrInvalidDelta
+					return
+				}
+				for i := int64(0); i < size; i++ {
+					window = append(window, data[0])
+				}
+				data = data[1:]
+			case instCopy:
+				here := segmentLength + int64(len(window))
+				var value int64
+				if in.mode >= 2+nearSize {
+					var b byte
+					b, err = addr.ReadByte()
+					value = int64(b)
+				} else {
+					value, err = readInteger(addr)
+				}
+				if err != nil {
+					err = ErrInvalidDelta
+					return
+				}
+				var address int64
+				if address, err = cache.decode(in.mode, value, here); err != nil {
+					return
+				}
+				// byte by byte, since a copy from the target may overlap the data it produces
+				for i := int64(0); i < size; i++ {
+					if position := address + i; position < segmentLength {
+						window = append(window, segment[position])
+					} else {
+						window = append(window, window[position-segmentLength])
+					}
+				}
+			}
+		}
+	}
+	if int64(len(window)) != targetLength || len(data) != 0 || addr.Len() != 0 {
+		err = ErrInvalidDelta
+		return
+	}
+	if hasChecksum && adler32.Checksum(window) != binary.BigEndian.Uint32(checksum[:]) {
+		err = ErrChecksum
+	}
+	return
+}
+
+// readInteger reads a variable-length integer (RFC 3284 section 2).
+func readInteger(reader io.ByteReader) (value int64, err error) {
+	for {
+		var b byte
+		if b, err = reader.ReadByte(); err != nil {
+			err = unexpectedEOF(err)
+			return
+		}
+		if value >= maxInteger>>7 {
+			err = ErrInvalidDelta
+			return
+		}
+		value = value<<7 | int64(b&0x7f)
+		if b&0x80 == 0 {
+			return
+		}
+	}
+}
+
+// unexpectedEOF reports a delta cut short as io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
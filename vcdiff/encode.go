@@ -0,0 +1,362 @@
+package vcdiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/adler32"
+	"io"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+	"github.com/kagalle/go-hashbuffer/delta"
+	"github.com/kagalle/go-hashbuffer/rolling"
+)
+
+const (
+	// DefaultWindowSize is the maximum number of target bytes in each window, when Encoder.WindowSize is 0.
+	DefaultWindowSize = 1 << 20
+	// MinBlockSize is the smallest HashBuffer window size that is used to find matches; with smaller
+	// windows (or a target shorter than its window) the whole target is added as literal data.
+	MinBlockSize = 4
+	// runs of at least this many identical bytes of literal data are encoded as RUN instructions
+	minRunLength = 6
+)
+
+// Encoder writes VCDIFF deltas.
+type Encoder struct {
+	// maximum number of target bytes in each window; 0 means DefaultWindowSize
+	WindowSize int
+	// add an Adler-32 checksum of each target window, the extension used by xdelta3 and open-vcdiff
+	Checksum bool
+}
+
+// Encode writes a VCDIFF delta from source to the remaining data in target using the default Encoder.
+func Encode(source []byte, target hashbuffer.HashBuffer, delta io.Writer) error {
+	return new(Encoder).Encode(source, target, delta)
+}
+
+// Encode writes a VCDIFF delta from source to the remaining data in target.
+//
+// The window size of target is the block size used to find matches: source is indexed in
+// non-overlapping blocks of that size, and the target window is rolled one byte at a time looking for
+// them.  Each match is extended past the block in both directions.  Smaller windows find shorter
+// matches, at the cost of a larger index; 16 to 64 bytes works well.
+func (e *Encoder) Encode(source []byte, target hashbuffer.HashBuffer, out io.Writer) (err error) {
+	w := &windowWriter{out: out, source: source, checksum: e.Checksum, windowSize: e.WindowSize}
+	if w.windowSize <= 0 {
+		w.windowSize = DefaultWindowSize
+	}
+	if _, err = out.Write(append(bytes.Clone(magic), 0)); err != nil {
+		return
+	}
+	window, err := target.GetWindow()
+	if err != nil || len(window) == 0 {
+		return
+	}
+	blockSize := len(window)
+	if blockSize < MinBlockSize || len(source) < blockSize {
+		err = encodeLiteral(target, window, w)
+		return
+	}
+
+	// index the source by the hash of each block, keeping the first of equal blocks
+	index := make(map[uint64]int)
+	hasher := rolling.NewRabinKarp()
+	for start := 0; start+blockSize <= len(source); start += blockSize {
+		hasher.Reset(source[start : start+blockSize])
+		if _, ok := index[hasher.Sum64()]; !ok {
+			index[hasher.Sum64()] = start
+		}
+	}
+
+	// literal data not yet written, which a match can extend backwards over
+	var literal []byte
+	rest, err := delta.Scan(target, window, hasher, func(window []byte, sum uint64) (next int64, ok bool, err error) {
+		start, found := index[sum]
+		if !found || !bytes.Equal(window, source[start:start+blockSize]) {
+			return
+		}
+		ok = true
+		targetStart := target.Offset()
+		// extend the match backwards over literal data not yet written
+		for len(literal) > 0 && start > 0 && literal[len(literal)-1] == source[start-1] {
+			literal = literal[:len(literal)-1]
+			start--
+			targetStart--
+		}
+		end := start + int(target.Offset()-targetStart) + blockSize
+		// extend the match forwards, one byte of the target at a time
+		for {
+			var nextByte byte
+			var more bool
+			nextByte, more, err = target.GetNext()
+			if err != nil {
+				return
+			}
+			if !more || end >= len(source) || source[end] != nextByte {
+				break
+			}
+			end++
+		}
+		if err = w.addLiteral(literal); err != nil {
+			return
+		}
+		literal = literal[:0]
+		if err = w.addCopy(int64(start), int64(end-start)); err != nil {
+			return
+		}
+		// go on with the window starting at the byte that did not match, or at the end of the target
+		next = targetStart + int64(end-start)
+		return
+	}, func(b byte) { literal = append(literal, b) })
+	if err != nil {
+		return
+	}
+	if err = w.addLiteral(append(literal, rest...)); err != nil {
+		return
+	}
+	err = w.flush(w.length)
+	return
+}
+
+// encodeLiteral writes the whole target, starting with window, as literal data.
+func encodeLiteral(target hashbuffer.HashBuffer, window []byte, w *windowWriter) (err error) {
+	literal := bytes.Clone(window)
+	for nextByte, err := range target.Bytes() {
+		if err != nil {
+			return err
+		}
+		literal = append(literal, nextByte)
+		if len(literal) >= w.windowSize {
+			if err = w.addLiteral(literal); err != nil {
+				return err
+			}
+			literal = literal[:0]
+		}
+	}
+	if err = w.addLiteral(literal); err != nil {
+		return
+	}
+	err = w.flush(w.length)
+	return
+}
+
+// op is a pending instruction, before it is assigned to a window.
+type op struct {
+	kind byte
+	// instAdd: the data; instRun: the byte to repeat
+	data []byte
+	// instCopy: source address
+	address int64
+	size    int64
+}
+
+// windowWriter collects instructions and writes them in windows of at most windowSize target bytes.
+type windowWriter struct {
+	out        io.Writer
+	source     []byte
+	checksum   bool
+	windowSize int
+	ops        []op
+	// number of target bytes the pending ops produce
+	length int64
+}
+
+// addLiteral adds data to be inserted, as ADD instructions with RUN instructions for long runs.
+func (w *windowWriter) addLiteral(data []byte) (err error) {
+	start := 0
+	for i := 0; i < len(data); {
+		run := 1
+		for i+run < len(data) && data[i+run] == data[i] {
+			run++
+		}
+		if run >= minRunLength {
+			if start < i {
+				if err = w.add(op{kind: instAdd, data: bytes.Clone(data[start:i]), size: int64(i - start)}); err != nil {
+					return
+				}
+			}
+			if err = w.add(op{kind: instRun, data: []byte{data[i]}, size: int64(run)}); err != nil {
+				return
+			}
+			start = i + run
+		}
+		i += run
+	}
+	if start < len(data) {
+		err = w.add(op{kind: instAdd, data: bytes.Clone(data[start:]), size: int64(len(data) - start)})
+	}
+	return
+}
+
+// addCopy adds a copy of size bytes at address in the source.
+func (w *windowWriter) addCopy(address int64, size int64) error {
+	return w.add(op{kind: instCopy, address: address, size: size})
+}
+
+func (w *windowWriter) add(o op) (err error) {
+	w.ops = append(w.ops, o)
+	w.length += o.size
+	for w.length >= int64(w.windowSize) && err == nil {
+		err = w.flush(int64(w.windowSize))
+	}
+	return
+}
+
+// flush writes a window of the first length bytes of pending target data, splitting an op if needed.
+func (w *windowWriter) flush(length int64) (err error) {
+	if length == 0 {
+		return
+	}
+	var window []op
+	remaining := length
+	for remaining > 0 {
+		o := w.ops[0]
+		if o.size > remaining {
+			// split the op at the window boundary
+			head, tail := o, o
+			head.size, tail.size = remaining, o.size-remaining
+			switch o.kind {
+			case instAdd:
+				head.data, tail.data = o.data[:remaining], o.data[remaining:]
+			case instCopy:
+				tail.address += remaining
+			}
+			w.ops[0] = tail
+			o = head
+		} else {
+			w.ops = w.ops[1:]
+		}
+		window = append(window, o)
+		remaining -= o.size
+	}
+	w.length -= length
+	_, err = w.out.Write(w.encodeWindow(window, length))
+	return
+}
+
+// encodeWindow encodes the ops of one window, which produce length bytes of target data.
+func (w *windowWriter) encodeWindow(ops []op, length int64) []byte {
+	// the source segment is the range of the source the copies use
+	segmentStart, segmentEnd := int64(-1), int64(0)
+	for _, o := range ops {
+		if o.kind == instCopy {
+			if segmentStart < 0 || o.address < segmentStart {
+				segmentStart = o.address
+			}
+			segmentEnd = max(segmentEnd, o.address+o.size)
+		}
+	}
+	if segmentStart < 0 {
+		// no copies
+		segmentStart = 0
+	}
+	segmentLength := segmentEnd - segmentStart
+
+	var data, inst, addr []byte
+	var cache addressCache
+	// the instructions, with their modes, so that ADD and COPY pairs can be combined
+	instructions := make([]instruction, len(ops))
+	here := segmentLength
+	for i, o := range ops {
+		instructions[i] = instruction{kind: o.kind}
+		switch o.kind {
+		case instAdd, instRun:
+			data = append(data, o.data...)
+		case instCopy:
+			mode, value := cache.encode(o.address-segmentStart, here)
+			instructions[i].mode = mode
+			if mode >= 2+nearSize {
+				addr = append(addr, byte(value))
+			} else {
+				addr = appendInteger(addr, value)
+			}
+		}
+		here += o.size
+	}
+	for i := 0; i < len(ops); i++ {
+		if i+1 < len(ops) {
+			if index, ok := pairIndex(instructions[i], ops[i].size, instructions[i+1], ops[i+1].size); ok {
+				inst = append(inst, index)
+				i++
+				continue
+			}
+		}
+		index, explicit := singleIndex(instructions[i], ops[i].size)
+		inst = append(inst, index)
+		if explicit {
+			inst = appendInteger(inst, ops[i].size)
+		}
+	}
+
+	var body []byte
+	body = appendInteger(body, length)
+	body = append(body, 0) // Delta_Indicator: no compression
+	body = appendInteger(body, int64(len(data)))
+	body = appendInteger(body, int64(len(inst)))
+	body = appendInteger(body, int64(len(addr)))
+	var indicator byte
+	if w.checksum {
+		indicator |= vcdAdler32
+		var target bytes.Buffer
+		w.rebuild(&target, ops)
+		body = binary.BigEndian.AppendUint32(body, adler32.Checksum(target.Bytes()))
+	}
+	body = append(append(append(body, data...), inst...), addr...)
+
+	var encoded []byte
+	if segmentLength > 0 {
+		indicator |= vcdSource
+		encoded = append(encoded, indicator)
+		encoded = appendInteger(encoded, segmentLength)
+		encoded = appendInteger(encoded, segmentStart)
+	} else {
+		encoded = append(encoded, indicator)
+	}
+	encoded = appendInteger(encoded, int64(len(body)))
+	return append(encoded, body...)
+}
+
+// rebuild writes the target data of a window, for its checksum.
+func (w *windowWriter) rebuild(target *bytes.Buffer, ops []op) {
+	for _, o := range ops {
+		switch o.kind {
+		case instAdd:
+			target.Write(o.data)
+		case instRun:
+			target.Write(bytes.Repeat(o.data, int(o.size)))
+		case instCopy:
+			target.Write(w.source[o.address : o.address+o.size])
+		}
+	}
+}
+
+// singleIndex returns the code table index for one instruction, and whether its size must follow it.
+func singleIndex(in instruction, size int64) (index byte, explicit bool) {
+	if size <= 0xff {
+		in.size = byte(size)
+		if index, ok := codeTableIndex[codeEntry{in}]; ok {
+			return index, false
+		}
+	}
+	in.size = 0
+	return codeTableIndex[codeEntry{in}], true
+}
+
+// pairIndex returns the code table index for two instructions together, if there is one.
+func pairIndex(first instruction, firstSize int64, second instruction, secondSize int64) (index byte, ok bool) {
+	if firstSize > 0xff || secondSize > 0xff {
+		return
+	}
+	first.size, second.size = byte(firstSize), byte(secondSize)
+	index, ok = codeTableIndex[codeEntry{first, second}]
+	return
+}
+
+// codeTableIndex maps each entry of the default code table back to its index.
+var codeTableIndex = func() map[codeEntry]byte {
+	index := make(map[codeEntry]byte)
+	for i, entry := range defaultCodeTable {
+		index[entry] = byte(i)
+	}
+	return index
+}()
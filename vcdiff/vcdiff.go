@@ -0,0 +1,174 @@
+// Package vcdiff encodes and decodes deltas in the VCDIFF format of RFC 3284.
+//
+// Encode finds matches between a source (the old data) and a target (the new data) read through a
+// hashbuffer.HashBuffer, and writes them as COPY, ADD and RUN instructions using the default code
+// table.  Decode applies any VCDIFF delta that uses the default code table and no secondary
+// compression, including the Adler-32 window checksum and application header written by xdelta3.
+package vcdiff
+
+import (
+	"errors"
+)
+
+// magic is the start of every VCDIFF delta: 'V', 'C', 'D' with their high bits set, then version 0.
+var magic = []byte{0xd6, 0xc3, 0xc4, 0x00}
+
+// Hdr_Indicator bits.
+const (
+	vcdDecompress = 0x01
+	vcdCodeTable  = 0x02
+	// xdelta3 extension: application-defined data follows the header
+	vcdAppHeader = 0x04
+)
+
+// Win_Indicator bits.
+const (
+	vcdSource = 0x01
+	vcdTarget = 0x02
+	// xdelta3 and open-vcdiff extension: an Adler-32 of the target window follows the section lengths
+	vcdAdler32 = 0x04
+)
+
+var (
+	// ErrInvalidDelta is returned when decoding data that is not a valid VCDIFF delta.
+	ErrInvalidDelta = errors.New("vcdiff: invalid delta")
+	// ErrUnsupported is returned for deltas using secondary compression or a custom code table.
+	ErrUnsupported = errors.New("vcdiff: secondary compression and custom code tables are not supported")
+	// ErrChecksum is returned when a window's Adler-32 checksum does not match the decoded data.
+	ErrChecksum = errors.New("vcdiff: window checksum mismatch")
+	// ErrTargetHistory is returned by Decode for a VCD_TARGET window that copies from further back in the
+	// target than it keeps in memory.
+	ErrTargetHistory = errors.New("vcdiff: VCD_TARGET window copies from before the target history kept; use DecodeTo")
+)
+
+// Instruction types.
+const (
+	instNoop = iota
+	instAdd
+	instRun
+	instCopy
+)
+
+// instruction is one half of a code table entry.
+type instruction struct {
+	kind byte
+	// 0 means the size follows in the instructions section
+	size byte
+	mode byte
+}
+
+// codeEntry is an entry of a code table: up to two instructions for one instruction-section byte.
+type codeEntry [2]instruction
+
+// Sizes of the address cache used with the default code table.
+const (
+	nearSize = 4
+	sameSize = 3
+	// modes 0 (self) and 1 (here), then the near modes, then the same modes
+	modeCount = 2 + nearSize + sameSize
+)
+
+// defaultCodeTable is the code table of RFC 3284 section 5.6.
+var defaultCodeTable = buildDefaultCodeTable()
+
+func buildDefaultCodeTable() (table [256]codeEntry) {
+	index := 0
+	add := func(first instruction, second instruction) {
+		table[index] = codeEntry{first, second}
+		index++
+	}
+	add(instruction{instRun, 0, 0}, instruction{})
+	for size := 0; size <= 17; size++ {
+		add(instruction{instAdd, byte(size), 0}, instruction{})
+	}
+	for mode := 0; mode < modeCount; mode++ {
+		add(instruction{instCopy, 0, byte(mode)}, instruction{})
+		for size := 4; size <= 18; size++ {
+			add(instruction{instCopy, byte(size), byte(mode)}, instruction{})
+		}
+	}
+	for mode := 0; mode < modeCount; mode++ {
+		for addSize := 1; addSize <= 4; addSize++ {
+			if mode <= 5 {
+				for copySize := 4; copySize <= 6; copySize++ {
+					add(instruction{instAdd, byte(addSize), 0}, instruction{instCopy, byte(copySize), byte(mode)})
+				}
+			} else {
+				add(instruction{instAdd, byte(addSize), 0}, instruction{instCopy, 4, byte(mode)})
+			}
+		}
+	}
+	for mode := 0; mode < modeCount; mode++ {
+		add(instruction{instCopy, 4, byte(mode)}, instruction{instAdd, 1, 0})
+	}
+	return
+}
+
+// addressCache holds the recently used COPY addresses, as in RFC 3284 section 5.1.
+// It is reset at the start of every window.
+type addressCache struct {
+	near     [nearSize]int64
+	nextSlot int
+	same     [sameSize * 256]int64
+}
+
+func (cache *addressCache) update(address int64) {
+	cache.near[cache.nextSlot] = address
+	cache.nextSlot = (cache.nextSlot + 1) % nearSize
+	cache.same[address%(sameSize*256)] = address
+}
+
+// encode returns the mode that gives the smallest encoding of address at here, and the value to write
+// (a single byte for the same modes, an integer otherwise).
+func (cache *addressCache) encode(address int64, here int64) (mode byte, value int64) {
+	value = address
+	if d := here - address; d < value {
+		value, mode = d, 1
+	}
+	for i, near := range cache.near {
+		if d := address - near; d >= 0 && d < value {
+			value, mode = d, byte(2+i)
+		}
+	}
+	if d := address % (sameSize * 256); cache.same[d] == address {
+		value, mode = d%256, byte(2+nearSize+d/256)
+	}
+	cache.update(address)
+	return
+}
+
+// decode returns the address for mode and the encoded value (a single byte for the same modes).
+func (cache *addressCache) decode(mode byte, value int64, here int64) (address int64, err error) {
+	switch {
+	case mode == 0:
+		address = value
+	case mode == 1:
+		address = here - value
+	case mode < 2+nearSize:
+		address = cache.near[mode-2] + value
+	case mode < modeCount:
+		address = cache.same[int64(mode-2-nearSize)*256+value]
+	default:
+		err = ErrInvalidDelta
+		return
+	}
+	if address < 0 || address >= here {
+		err = ErrInvalidDelta
+		return
+	}
+	cache.update(address)
+	return
+}
+
+// appendInteger appends value in the variable-length integer format of RFC 3284 section 2:
+// base 128, most significant digit first, with the high bit set on all but the last byte.
+func appendInteger(data []byte, value int64) []byte {
+	var digits [10]byte
+	i := len(digits) - 1
+	digits[i] = byte(value & 0x7f)
+	for value >>= 7; value > 0; value >>= 7 {
+		i--
+		digits[i] = byte(value&0x7f) | 0x80
+	}
+	return append(data, digits[i:]...)
+}
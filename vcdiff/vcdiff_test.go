@@ -0,0 +1,184 @@
+package vcdiff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	hashbuffer "github.com/kagalle/go-hashbuffer"
+)
+
+// Make sure known-good deltas, assembled by hand from RFC 3284, decode to the expected targets.  There are
+// no deltas written by xdelta3 or open-vcdiff: neither was available where the package was written, so
+// interoperability with them rests on following the RFC and their documented Adler-32 extension.
+func TestDecodeSamples(t *testing.T) {
+	// the example of RFC 3284 section 3: COPY 4,0; ADD 4,wxyz; COPY 4,4; COPY 12,24; RUN 4,z
+	testDecodeSample(t, "TestDecodeSamples_rfc3284", "rfc3284_source", "rfc3284.vcdiff", "rfc3284_target")
+	// an application header, a window copying from itself, and a VCD_TARGET window with a checksum
+	testDecodeSample(t, "TestDecodeSamples_target_window", "", "target_window.vcdiff", "target_window_target")
+}
+
+// Make sure DecodeTo reads VCD_TARGET windows back from the target, and reports a target it cannot read.
+func TestDecodeTo(t *testing.T) {
+	const title = "TestDecodeTo"
+
+	name := filepath.Join(t.TempDir(), "target")
+	target, err := os.Create(name)
+	check(t, err)
+	defer target.Close()
+	check(t, DecodeTo(nil, bytes.NewReader(readTestData(t, "target_window.vcdiff")), target))
+	data, err := os.ReadFile(name)
+	check(t, err)
+	if !bytes.Equal(data, readTestData(t, "target_window_target")) {
+		t.Errorf("Error %s: decoded %q", title, data)
+	}
+	for _, flag := range []int{os.O_WRONLY, os.O_RDWR | os.O_APPEND} {
+		target, err := os.OpenFile(name, flag|os.O_TRUNC, 0o644)
+		check(t, err)
+		if err = DecodeTo(nil, bytes.NewReader(readTestData(t, "target_window.vcdiff")), target); err == nil {
+			t.Errorf("Error %s: decoding to a file opened with flags %#x succeeded", title, flag)
+		}
+		target.Close()
+	}
+}
+
+// Make sure Decode reports a VCD_TARGET window copying from before the target it keeps.
+func TestDecodeTargetHistory(t *testing.T) {
+	d := newDecoder(nil, bytes.NewReader(readTestData(t, "target_window.vcdiff")))
+	d.target = io.Discard
+	d.historySize = 1
+	if err := d.decode(); !errors.Is(err, ErrTargetHistory) {
+		t.Errorf("Error TestDecodeTargetHistory: got %v, want %v", err, ErrTargetHistory)
+	}
+}
+
+// Make sure what is encoded decodes back to the target, for pairs of the test files.
+func TestRoundTripFiles(t *testing.T) {
+	files := []string{"data_0", "data_1", "data_15", "data_17", "data_1024", "data_1025", "data_long"}
+	for _, sourceFile := range files {
+		for _, targetFile := range files {
+			source := readTestData(t, "../"+sourceFile)
+			hb, err := hashbuffer.NewFileHashBuffer("../testdata/"+targetFile, 1024, 16)
+			check(t, err)
+			testRoundTrip(t, fmt.Sprintf("TestRoundTripFiles_%s_%s", sourceFile, targetFile),
+				new(Encoder), source, readTestData(t, "../"+targetFile), hb)
+		}
+	}
+}
+
+// Make sure edits are encoded compactly, across several windows, with and without checksums.
+func TestRoundTripEdits(t *testing.T) {
+	source := readTestData(t, "../data_long")
+	edits := map[string][]byte{
+		"identical": source,
+		"insert":    concat(source[:1000], []byte("inserted text"), source[1000:]),
+		"delete":    concat(source[:1000], source[1500:]),
+		"modify":    concat(source[:20000], []byte("XXXX"), source[20004:]),
+		"run":       concat(source[:5000], bytes.Repeat([]byte{'z'}, 3000), source[5000:]),
+		"reorder":   concat(source[20000:], source[:20000]),
+		"unrelated": bytes.Repeat([]byte("0123456789abcdef"), 100),
+	}
+	encoders := []*Encoder{{}, {WindowSize: 1000, Checksum: true}, {WindowSize: 7}}
+	for name, edited := range edits {
+		for i, encoder := range encoders {
+			title := fmt.Sprintf("TestRoundTripEdits_%s_%d", name, i)
+			hb, err := hashbuffer.NewBytesHashBuffer(edited, 32)
+			check(t, err)
+			delta := testRoundTrip(t, title, encoder, source, edited, hb)
+			if encoder.WindowSize == 0 && name != "unrelated" && len(delta) > 200 {
+				t.Errorf("Error %s: delta is %d bytes", title, len(delta))
+			}
+		}
+	}
+}
+
+// Make sure a match followed by a few unmatched bytes at the end of the target keeps them, whether the
+// window after the match is full, short or missing, with buffers that are refilled along the way.
+func TestRoundTripTails(t *testing.T) {
+	source := readTestData(t, "../data_long")
+	for tail := 0; tail <= 40; tail++ {
+		for _, bufferSize := range []int{32, 1024} {
+			title := fmt.Sprintf("TestRoundTripTails_%d_%d", tail, bufferSize)
+			edited := concat(source[:1000], bytes.Repeat([]byte{'~'}, tail))
+			hb, err := hashbuffer.NewReaderHashBuffer(bytes.NewReader(edited), bufferSize, 16)
+			check(t, err)
+			testRoundTrip(t, title, new(Encoder), source, edited, hb)
+		}
+	}
+}
+
+// Make sure corrupt and unsupported deltas are rejected.
+func TestDecodeInvalid(t *testing.T) {
+	sample := readTestData(t, "target_window.vcdiff")
+	tests := []struct {
+		name  string
+		delta []byte
+		want  error
+	}{
+		{"truncated", sample[:len(sample)-1], io.ErrUnexpectedEOF},
+		{"magic", concat([]byte{0xd6, 0xc3, 0xc4, 0x01}, sample[4:]), ErrInvalidDelta},
+		{"secondary", concat(sample[:4], []byte{vcdDecompress}, sample[5:]), ErrUnsupported},
+		{"codetable", concat(sample[:4], []byte{vcdCodeTable}, sample[5:]), ErrUnsupported},
+		{"checksum", concat(sample[:len(sample)-13], []byte{sample[len(sample)-13] ^ 1}, sample[len(sample)-12:]), ErrChecksum},
+		{"source", readTestData(t, "rfc3284.vcdiff"), ErrInvalidDelta},
+	}
+	for _, test := range tests {
+		err := Decode(nil, bytes.NewReader(test.delta), io.Discard)
+		if !errors.Is(err, test.want) {
+			t.Errorf("Error TestDecodeInvalid_%s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func testDecodeSample(t *testing.T, title string, sourceFile string, deltaFile string, targetFile string) {
+	var source io.ReaderAt
+	if sourceFile != "" {
+		source = bytes.NewReader(readTestData(t, sourceFile))
+	}
+	var target bytes.Buffer
+	check(t, Decode(source, bytes.NewReader(readTestData(t, deltaFile)), &target))
+	if want := readTestData(t, targetFile); !bytes.Equal(target.Bytes(), want) {
+		t.Errorf("Error %s: decoded %q, want %q", title, target.Bytes(), want)
+	}
+}
+
+func testRoundTrip(t *testing.T, title string, encoder *Encoder, source []byte, want []byte, hb hashbuffer.HashBuffer) []byte {
+	t.Helper()
+	defer hb.Close()
+	var delta bytes.Buffer
+	check(t, encoder.Encode(source, hb, &delta))
+	var target bytes.Buffer
+	if err := Decode(bytes.NewReader(source), bytes.NewReader(delta.Bytes()), &target); err != nil {
+		t.Fatalf("Error %s: decode: %v", title, err)
+	}
+	if !bytes.Equal(target.Bytes(), want) {
+		t.Fatalf("Error %s: decoded %d bytes that do not match the %d expected", title, target.Len(), len(want))
+	}
+	return delta.Bytes()
+}
+
+func concat(parts ...[]byte) (result []byte) {
+	for _, part := range parts {
+		result = append(result, part...)
+	}
+	return
+}
+
+// readTestData reads a file from testdata/vcdiff.
+func readTestData(t *testing.T, filename string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("../testdata/vcdiff", filename))
+	check(t, err)
+	return data
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Error %v", err)
+	}
+}